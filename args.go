@@ -0,0 +1,207 @@
+package gredis
+
+import (
+	"encoding"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/valery-barysok/resp"
+)
+
+// AppendArg appends wire representation of v to buf. Supported types are string, []byte, all integer and
+// float kinds, bool, which is sent as 1 or 0, and types implementing encoding.BinaryMarshaler. Numbers are
+// formatted directly into buf without intermediate strings.
+func AppendArg(buf []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return append(buf, v...), nil
+	case []byte:
+		return append(buf, v...), nil
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(buf, v, 10), nil
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(buf, v, 10), nil
+	case float32:
+		return strconv.AppendFloat(buf, float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.AppendFloat(buf, v, 'g', -1, 64), nil
+	case bool:
+		if v {
+			return append(buf, '1'), nil
+		}
+		return append(buf, '0'), nil
+	case encoding.BinaryMarshaler:
+		data, err := v.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return append(buf, data...), nil
+	}
+
+	return nil, fmt.Errorf("unsupported argument type %T", v)
+}
+
+// Args converts values to command arguments, see AppendArg for supported types. []byte values are used
+// as is without copying.
+func Args(values ...interface{}) ([][]byte, error) {
+	res := make([][]byte, 0, len(values))
+	for _, v := range values {
+		if b, ok := v.([]byte); ok {
+			res = append(res, b)
+			continue
+		}
+
+		arg, err := AppendArg(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, arg)
+	}
+
+	return res, nil
+}
+
+// DoArgs converts args like Args and sends command to GRedis server, see Do. Arguments are built in pooled
+// buffers.
+func (client *Client) DoArgs(cmd []byte, args ...interface{}) (*resp.Message, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	for _, v := range args {
+		if err := a.add(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return client.Do(cmd, a.bulks()...)
+}
+
+// maxPooledArgsLen limits size of buffers returned to argsPool, so rare huge commands do not pin memory
+const maxPooledArgsLen = 64 << 10
+
+var argsPool = sync.Pool{
+	New: func() interface{} {
+		return &cmdArgs{buf: make([]byte, 0, 128)}
+	},
+}
+
+// cmdArgs builds command arguments in a reusable buffer. Strings and numbers are appended to buf, []byte
+// values are referenced as is. Slices returned by bulks are valid until cmdArgs is returned to the pool.
+type cmdArgs struct {
+	buf   []byte
+	spans []argSpan
+	args  [][]byte
+}
+
+// argSpan is either a range of buf or an external value
+type argSpan struct {
+	start int
+	end   int
+	ext   []byte
+}
+
+func getCmdArgs() *cmdArgs {
+	return argsPool.Get().(*cmdArgs)
+}
+
+func putCmdArgs(a *cmdArgs) {
+	if cap(a.buf) > maxPooledArgsLen {
+		return
+	}
+
+	a.reset()
+	argsPool.Put(a)
+}
+
+// reset drops arguments keeping buffers, so values referenced by them can be collected
+func (a *cmdArgs) reset() {
+	for i := range a.args {
+		a.args[i] = nil
+	}
+	for i := range a.spans {
+		a.spans[i].ext = nil
+	}
+	a.buf = a.buf[:0]
+	a.spans = a.spans[:0]
+	a.args = a.args[:0]
+}
+
+func (a *cmdArgs) addString(s string) *cmdArgs {
+	start := len(a.buf)
+	a.buf = append(a.buf, s...)
+	a.spans = append(a.spans, argSpan{start: start, end: len(a.buf)})
+	return a
+}
+
+func (a *cmdArgs) addStrings(ss []string) *cmdArgs {
+	for _, s := range ss {
+		a.addString(s)
+	}
+	return a
+}
+
+func (a *cmdArgs) addInt(n int) *cmdArgs {
+	start := len(a.buf)
+	a.buf = strconv.AppendInt(a.buf, int64(n), 10)
+	a.spans = append(a.spans, argSpan{start: start, end: len(a.buf)})
+	return a
+}
+
+// add appends v converted with AppendArg, []byte values are referenced as is
+func (a *cmdArgs) add(v interface{}) error {
+	if b, ok := v.([]byte); ok {
+		a.addBytes(b)
+		return nil
+	}
+
+	start := len(a.buf)
+	buf, err := AppendArg(a.buf, v)
+	if err != nil {
+		return err
+	}
+
+	a.buf = buf
+	a.spans = append(a.spans, argSpan{start: start, end: len(a.buf)})
+	return nil
+}
+
+func (a *cmdArgs) addBytes(b []byte) *cmdArgs {
+	a.spans = append(a.spans, argSpan{ext: b})
+	return a
+}
+
+func (a *cmdArgs) addBytesList(bs [][]byte) *cmdArgs {
+	for _, b := range bs {
+		a.addBytes(b)
+	}
+	return a
+}
+
+// bulks returns the arguments, ranges of buf are resolved only now because appending may move buf
+func (a *cmdArgs) bulks() [][]byte {
+	for _, span := range a.spans {
+		if span.ext != nil {
+			a.args = append(a.args, span.ext)
+		} else {
+			a.args = append(a.args, a.buf[span.start:span.end:span.end])
+		}
+	}
+	return a.args
+}
@@ -0,0 +1,124 @@
+package gredis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+func TestArgs(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tsData, err := ts.MarshalBinary()
+	Expect(err).ToNot(HaveOccurred())
+
+	raw := []byte{0, '\r', '\n', 0xff}
+	args, err := Args("key", raw, 42, int8(-8), uint64(1<<63), 1.5, float32(0.25), true, false, ts)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(args).To(Equal([][]byte{
+		[]byte("key"),
+		raw,
+		[]byte("42"),
+		[]byte("-8"),
+		[]byte("9223372036854775808"),
+		[]byte("1.5"),
+		[]byte("0.25"),
+		[]byte("1"),
+		[]byte("0"),
+		tsData,
+	}))
+
+	// []byte values are passed without copying
+	Expect(&args[1][0]).To(BeIdenticalTo(&raw[0]))
+
+	_, err = Args(struct{}{})
+	Expect(err).To(MatchError("unsupported argument type struct {}"))
+
+	buf, err := AppendArg([]byte("n="), -123)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(string(buf)).To(Equal("n=-123"))
+}
+
+func TestCmdArgs(t *testing.T) {
+	RegisterTestingT(t)
+
+	long := strings.Repeat("v", 1000)
+	raw := []byte{0, 1, 2}
+
+	for i := 0; i < 2; i++ {
+		// arguments stay valid when buffer grows while they are added
+		a := getCmdArgs()
+		args := a.addString("key").addInt(-42).addBytes(raw).addString(long).addStrings([]string{"x", "y"}).bulks()
+		Expect(args).To(Equal([][]byte{
+			[]byte("key"), []byte("-42"), raw, []byte(long), []byte("x"), []byte("y"),
+		}))
+		Expect(&args[2][0]).To(BeIdenticalTo(&raw[0]))
+
+		// appending to an argument must not overwrite the next one
+		_ = append(args[0], "zzz"...)
+		Expect(args[1]).To(Equal([]byte("-42")))
+
+		putCmdArgs(a)
+	}
+}
+
+func TestCmdArgsAllocs(t *testing.T) {
+	RegisterTestingT(t)
+
+	key, field, value := "hash:000001", "field", []byte("value:000001")
+
+	// buffers are reused once they have grown
+	a := &cmdArgs{}
+	allocs := testing.AllocsPerRun(100, func() {
+		a.addString(key).addString(field).addBytes(value).addInt(12345).bulks()
+		if err := a.add(67890); err != nil {
+			t.Fatal(err)
+		}
+		a.reset()
+	})
+	Expect(allocs).To(BeNumerically("==", 0))
+}
+
+func TestBinarySafeCommands(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	value := []byte{0, 1, '\r', '\n', 0xfe, 0xff}
+
+	ok, err := client.SetBytes("bin", value)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(ok).To(BeTrue())
+	Expect(client.Get("bin")).To(Equal(value))
+
+	Expect(client.RPushBytes("binlist", value, []byte{})).To(Equal(2))
+	Expect(client.LPushBytes("binlist", []byte("\x00head"))).To(Equal(3))
+	Expect(client.LInsertBytes("binlist", false, value, []byte("\r\n"))).To(Equal(4))
+	Expect(client.LRange("binlist", 0, -1)).To(Equal([][]byte{
+		[]byte("\x00head"), value, []byte("\r\n"), {},
+	}))
+
+	Expect(client.HSetBytes("binhash", "f", value)).To(Equal(1))
+	Expect(client.HGet("binhash", "f")).To(Equal(value))
+
+	_, err = client.DoArgs(SetCommand, "num", 12345)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client.Get("num")).To(Equal([]byte("12345")))
+
+	_, err = client.DoArgs(SetCommand, "bad", struct{}{})
+	Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,310 @@
+package gredis
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strconv"
+	"testing"
+)
+
+var (
+	benchIntReply  = []byte(":1\r\n")
+	benchOKReply   = []byte("+OK\r\n")
+	benchBulkReply = []byte("$3\r\nbar\r\n")
+)
+
+// benchServer parses commands and replies with canned replies without allocating, so allocations reported
+// by benchmarks are made by client only
+type benchServer struct {
+	l net.Listener
+}
+
+func newBenchServer(b *testing.B) *benchServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	s := &benchServer{l: l}
+	go s.serve()
+
+	return s
+}
+
+func (s *benchServer) serve() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *benchServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		n, err := readBenchInt(r)
+		if err != nil {
+			return
+		}
+
+		reply := benchIntReply
+		for i := 0; i < n; i++ {
+			size, err := readBenchInt(r)
+			if err != nil {
+				return
+			}
+
+			if i == 0 {
+				name, err := r.Peek(size)
+				if err != nil {
+					return
+				}
+				switch {
+				case bytes.Equal(name, GetCommand), bytes.Equal(name, HGetCommand):
+					reply = benchBulkReply
+				case bytes.Equal(name, SetCommand):
+					reply = benchOKReply
+				}
+			}
+
+			if _, err := r.Discard(size + 2); err != nil {
+				return
+			}
+		}
+
+		w.Write(reply)
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readBenchInt reads `*N\r\n` or `$N\r\n` line and returns N
+func readBenchInt(r *bufio.Reader) (int, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, c := range line[1 : len(line)-2] {
+		n = n*10 + int(c-'0')
+	}
+
+	return n, nil
+}
+
+func (s *benchServer) dial(b *testing.B) *Client {
+	opts, err := NewOptions("gredis://" + s.l.Addr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	client, err := Dial(opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return client
+}
+
+// Benchmarks measure allocations of high level methods against benchServer. Run them on two commits and
+// compare the results with benchstat, or compare BenchmarkX with BenchmarkXBaseline, which builds arguments
+// the way high level methods did before pooled buffers.
+//
+// Allocations left are made by resp outside of this package: Writer.WriteCmd formats length header of every
+// argument and Reader.Read allocates Message of every reply. BenchmarkArgs shows that building arguments
+// does not allocate. BenchmarkSetValue makes one more allocation in the benchmark itself, boxing value into
+// interface{}.
+
+// toBulkArray converts arguments the way high level methods did before pooled buffers
+func toBulkArray(args []string, keys ...string) [][]byte {
+	res := make([][]byte, 0, len(args)+len(keys))
+
+	for _, value := range keys {
+		res = append(res, []byte(value))
+	}
+
+	for _, value := range args {
+		res = append(res, []byte(value))
+	}
+
+	return res
+}
+
+func BenchmarkArgs(b *testing.B) {
+	values := []string{"a", "b", "c"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		a := getCmdArgs()
+		a.addString("list:000001").addInt(i).addStrings(values).bulks()
+		putCmdArgs(a)
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	s := newBenchServer(b)
+	defer s.l.Close()
+
+	client := s.dial(b)
+	defer client.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Set("key:000001", "value:000001"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSetBaseline(b *testing.B) {
+	s := newBenchServer(b)
+	defer s.l.Close()
+
+	client := s.dial(b)
+	defer client.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Do(SetCommand, []byte("key:000001"), []byte("value:000001")); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	s := newBenchServer(b)
+	defer s.l.Close()
+
+	client := s.dial(b)
+	defer client.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Get("key:000001"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetBaseline(b *testing.B) {
+	s := newBenchServer(b)
+	defer s.l.Close()
+
+	client := s.dial(b)
+	defer client.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Do(GetCommand, []byte("key:000001")); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLPush(b *testing.B) {
+	s := newBenchServer(b)
+	defer s.l.Close()
+
+	client := s.dial(b)
+	defer client.Close()
+
+	values := []string{"a", "b", "c"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.LPush("list:000001", strconv.Itoa(i), values...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLPushBaseline(b *testing.B) {
+	s := newBenchServer(b)
+	defer s.l.Close()
+
+	client := s.dial(b)
+	defer client.Close()
+
+	values := []string{"a", "b", "c"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Do(LPushCommand, toBulkArray(values, "list:000001", strconv.Itoa(i))...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHSet(b *testing.B) {
+	s := newBenchServer(b)
+	defer s.l.Close()
+
+	client := s.dial(b)
+	defer client.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.HSet("hash:000001", "field", "value:000001"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHSetBaseline(b *testing.B) {
+	s := newBenchServer(b)
+	defer s.l.Close()
+
+	client := s.dial(b)
+	defer client.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Do(HSetCommand, []byte("hash:000001"), []byte("field"), []byte("value:000001")); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSetValue(b *testing.B) {
+	s := newBenchServer(b)
+	defer s.l.Close()
+
+	client := s.dial(b)
+	defer client.Close()
+	client.opts.Codec = RawCodec{}
+
+	value := []byte("value:000001")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.SetValue("key:000001", value); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDoArgs(b *testing.B) {
+	s := newBenchServer(b)
+	defer s.l.Close()
+
+	client := s.dial(b)
+	defer client.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.DoArgs(SetCommand, "key:000001", 12345); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
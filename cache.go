@@ -0,0 +1,277 @@
+package gredis
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by Loader to report that value does not exist. Cache remembers it for
+// CacheOptions.NegativeTTL and GetOrLoad returns it to callers.
+var ErrNotFound = errors.New("not found")
+
+const (
+	entryValue byte = iota
+	entryNotFound
+)
+
+// entryHeaderLen is the length of marker byte followed by fresh until time in unix nanoseconds, zero time
+// means the entry never goes stale
+const entryHeaderLen = 9
+
+const defaultLoadTimeout = 10 * time.Second
+
+// Loader computes value of key on cache miss
+type Loader func(ctx context.Context) (interface{}, error)
+
+// CacheOptions provides settings for Cache
+type CacheOptions struct {
+	// Codec encodes cached values, codec of the client is used by default
+	Codec Codec
+	// NegativeTTL is the time ErrNotFound returned by loader is remembered, not found results are not cached
+	// when zero
+	NegativeTTL time.Duration
+	// StaleTTL is the time value is kept after its TTL has passed. Stale value is returned immediately while
+	// it is reloaded in background.
+	StaleTTL time.Duration
+	// LoadTimeout limits a single loader call, 10 seconds by default. Loader gets ctx with this deadline and
+	// waiters get context.DeadlineExceeded when it passes, even if loader ignores ctx.
+	LoadTimeout time.Duration
+}
+
+// Cache implements cache-aside pattern on top of GRedis server. Concurrent loads of the same key are
+// coalesced into a single call of loader. Cache is safe for concurrent use.
+//
+// Values are stored with a small header holding their freshness, so keys used by Cache should not be
+// modified by other commands.
+type Cache struct {
+	client *Client
+	opts   CacheOptions
+	now    func() time.Time
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done  chan struct{}
+	entry []byte
+	err   error
+}
+
+// NewCache returns cache storing values through client
+func NewCache(client *Client, opts CacheOptions) *Cache {
+	if opts.Codec == nil {
+		opts.Codec = client.codec()
+	}
+	if opts.LoadTimeout == 0 {
+		opts.LoadTimeout = defaultLoadTimeout
+	}
+
+	return &Cache{
+		client:  client,
+		opts:    opts,
+		now:     time.Now,
+		flights: make(map[string]*flight),
+	}
+}
+
+// GetOrLoad decodes cached value of key into v. On cache miss loader is called, its result is stored for
+// ttl and decoded into v, values loaded with ttl <= 0 never expire. Only one loader per key runs at a time
+// and concurrent callers wait for its result until their own ctx is done. The loader is shared, so it gets
+// ctx of the caller that started it detached from its cancellation and deadline: values of ctx are kept,
+// but the caller giving up does not fail the rest of waiters. The loader is limited by
+// CacheOptions.LoadTimeout instead.
+//
+// Stale value is returned as is while a background load refreshes it, see CacheOptions.StaleTTL. Errors of
+// loader other than ErrNotFound are returned without caching.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader Loader, v interface{}) error {
+	data, err := c.client.Get(key)
+	if err != nil {
+		return err
+	}
+
+	if freshUntil, ok := parseEntry(data); ok {
+		if !freshUntil.IsZero() && c.now().After(freshUntil) {
+			c.load(context.Background(), key, ttl, loader)
+		}
+		return c.decode(data, v)
+	}
+
+	f := c.load(detach(ctx), key, ttl, loader)
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if f.err != nil {
+		return f.err
+	}
+
+	return c.decode(f.entry, v)
+}
+
+// detachedContext keeps values of its parent but is never canceled
+type detachedContext struct {
+	parent context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
+
+// Del removes cached value of key
+func (c *Cache) Del(key string) error {
+	_, err := c.client.Del(key)
+	return err
+}
+
+// load starts loader for key unless it is already running and returns the flight to wait for
+func (c *Cache) load(ctx context.Context, key string, ttl time.Duration, loader Loader) *flight {
+	c.mu.Lock()
+	if f, ok := c.flights[key]; ok {
+		c.mu.Unlock()
+		return f
+	}
+
+	f := &flight{done: make(chan struct{})}
+	c.flights[key] = f
+	c.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, c.opts.LoadTimeout)
+		defer cancel()
+
+		f.entry, f.err = c.fill(ctx, key, ttl, loader)
+
+		c.mu.Lock()
+		delete(c.flights, key)
+		c.mu.Unlock()
+
+		close(f.done)
+	}()
+
+	return f
+}
+
+// fill calls loader and stores its result
+func (c *Cache) fill(ctx context.Context, key string, ttl time.Duration, loader Loader) ([]byte, error) {
+	value, err := callLoader(ctx, loader)
+	if err == ErrNotFound {
+		if c.opts.NegativeTTL <= 0 {
+			return nil, err
+		}
+
+		entry := c.newEntry(entryNotFound, c.opts.NegativeTTL, nil)
+		if err := c.store(key, entry, c.opts.NegativeTTL); err != nil {
+			return nil, err
+		}
+		return entry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := c.opts.Codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	expire := time.Duration(0)
+	if ttl > 0 {
+		expire = ttl + c.opts.StaleTTL
+	}
+
+	entry := c.newEntry(entryValue, ttl, payload)
+	if err := c.store(key, entry, expire); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// callLoader returns result of loader or error of ctx if it is done first, loader ignoring ctx is left
+// running in background
+func callLoader(ctx context.Context, loader Loader) (interface{}, error) {
+	type result struct {
+		value interface{}
+		err   error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		value, err := loader(ctx)
+		done <- result{value, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Cache) newEntry(marker byte, ttl time.Duration, payload []byte) []byte {
+	entry := make([]byte, entryHeaderLen, entryHeaderLen+len(payload))
+	entry[0] = marker
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(entry[1:], uint64(c.now().Add(ttl).UnixNano()))
+	}
+
+	return append(entry, payload...)
+}
+
+// store sets key to entry expiring after ttl rounded up to milliseconds, entry never expires when ttl <= 0
+func (c *Cache) store(key string, entry []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		_, err := c.client.Do(SetCommand, []byte(key), entry)
+		return err
+	}
+
+	ms := strconv.FormatInt(int64((ttl+time.Millisecond-1)/time.Millisecond), 10)
+	_, err := c.client.Do(SetCommand, []byte(key), entry, pxArg, []byte(ms))
+	return err
+}
+
+func (c *Cache) decode(entry []byte, v interface{}) error {
+	if entry[0] == entryNotFound {
+		return ErrNotFound
+	}
+
+	return c.opts.Codec.Unmarshal(entry[entryHeaderLen:], v)
+}
+
+// parseEntry returns fresh until time of entry, false is returned if data is not a valid entry
+func parseEntry(data []byte) (time.Time, bool) {
+	if len(data) < entryHeaderLen || data[0] > entryNotFound {
+		return time.Time{}, false
+	}
+
+	nanos := int64(binary.BigEndian.Uint64(data[1:]))
+	if nanos == 0 {
+		return time.Time{}, true
+	}
+
+	return time.Unix(0, nanos), true
+}
@@ -0,0 +1,318 @@
+package gredis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+func TestCache(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	cache := NewCache(client, CacheOptions{NegativeTTL: time.Minute, StaleTTL: time.Minute})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	// concurrent misses are coalesced into a single load
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return codecRecord{Name: "a", Count: 1}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]codecRecord, 10)
+	errs := make([]error, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = cache.GetOrLoad(ctx, "user", time.Minute, loader, &results[i])
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+	for i := range results {
+		Expect(errs[i]).ToNot(HaveOccurred())
+		Expect(results[i]).To(Equal(codecRecord{Name: "a", Count: 1}))
+	}
+
+	// cached value is returned without calling loader
+	var rec codecRecord
+	Expect(cache.GetOrLoad(ctx, "user", time.Minute, loader, &rec)).To(Succeed())
+	Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+
+	// stale value is returned while it is reloaded in background
+	now = now.Add(2 * time.Minute)
+	reloaded := make(chan struct{})
+	refresh := func(ctx context.Context) (interface{}, error) {
+		defer close(reloaded)
+		return codecRecord{Name: "b", Count: 2}, nil
+	}
+	Expect(cache.GetOrLoad(ctx, "user", time.Minute, refresh, &rec)).To(Succeed())
+	Expect(rec).To(Equal(codecRecord{Name: "a", Count: 1}))
+
+	<-reloaded
+	Eventually(func() codecRecord {
+		var rec codecRecord
+		cache.GetOrLoad(ctx, "user", time.Minute, loader, &rec)
+		return rec
+	}).Should(Equal(codecRecord{Name: "b", Count: 2}))
+
+	// not found result is cached
+	calls = 0
+	missing := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, ErrNotFound
+	}
+	Expect(cache.GetOrLoad(ctx, "missing", time.Minute, missing, &rec)).To(Equal(ErrNotFound))
+	Expect(cache.GetOrLoad(ctx, "missing", time.Minute, missing, &rec)).To(Equal(ErrNotFound))
+	Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+
+	// other errors are not cached
+	failing := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("db is down")
+	}
+	Expect(cache.GetOrLoad(ctx, "failing", time.Minute, failing, &rec)).To(MatchError("db is down"))
+	Expect(cache.GetOrLoad(ctx, "failing", time.Minute, failing, &rec)).To(MatchError("db is down"))
+	Expect(atomic.LoadInt32(&calls)).To(Equal(int32(3)))
+
+	Expect(cache.Del("user")).To(Succeed())
+	Expect(client.Exists("user")).To(Equal(0))
+
+	// value loaded with zero ttl never expires
+	Expect(cache.GetOrLoad(ctx, "forever", 0, loader, &rec)).To(Succeed())
+	Expect(client.TTL("forever")).To(Equal(-1))
+}
+
+func TestCacheContextDone(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	cache := NewCache(client, CacheOptions{})
+
+	release := make(chan struct{})
+	defer close(release)
+	slow := func(ctx context.Context) (interface{}, error) {
+		<-release
+		return "late", nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	var s string
+	Expect(cache.GetOrLoad(ctx, "slow", time.Minute, slow, &s)).To(Equal(context.DeadlineExceeded))
+}
+
+func TestCacheWaiterOutlivesCanceledCaller(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	cache := NewCache(client, CacheOptions{})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return "value", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		var v string
+		firstErr <- cache.GetOrLoad(first, "key", time.Minute, loader, &v)
+	}()
+	<-started
+
+	secondErr := make(chan error)
+	var second string
+	go func() {
+		secondErr <- cache.GetOrLoad(context.Background(), "key", time.Minute, loader, &second)
+	}()
+
+	cancel()
+	Expect(<-firstErr).To(Equal(context.Canceled))
+
+	close(release)
+	Expect(<-secondErr).ToNot(HaveOccurred())
+	Expect(second).To(Equal("value"))
+}
+
+// argsHook records commands with their arguments
+type argsHook struct {
+	mu   sync.Mutex
+	cmds [][]string
+}
+
+func (h *argsHook) ProcessHook(next ProcessFunc) ProcessFunc {
+	return func(cmd *Cmd) error {
+		args := []string{string(cmd.Name)}
+		for _, arg := range cmd.Args {
+			args = append(args, string(arg))
+		}
+
+		h.mu.Lock()
+		h.cmds = append(h.cmds, args)
+		h.mu.Unlock()
+
+		return next(cmd)
+	}
+}
+
+func (h *argsHook) ProcessPipelineHook(next ProcessPipelineFunc) ProcessPipelineFunc {
+	return func(cmds []*Cmd) error {
+		for _, cmd := range cmds {
+			h.mu.Lock()
+			h.cmds = append(h.cmds, []string{"pipeline " + string(cmd.Name)})
+			h.mu.Unlock()
+		}
+
+		return next(cmds)
+	}
+}
+
+func TestCacheStoreTTL(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	hook := &argsHook{}
+	client.AddHook(hook)
+
+	cache := NewCache(client, CacheOptions{StaleTTL: time.Second})
+	loader := func(ctx context.Context) (interface{}, error) {
+		return "value", nil
+	}
+
+	var v string
+	Expect(cache.GetOrLoad(context.Background(), "forever", 0, loader, &v)).To(Succeed())
+	Expect(cache.GetOrLoad(context.Background(), "short", 1500*time.Microsecond, loader, &v)).To(Succeed())
+
+	var names []string
+	var sets [][]string
+	for _, cmd := range hook.cmds {
+		names = append(names, cmd[0])
+		if cmd[0] == "SET" {
+			// drop the entry
+			sets = append(sets, append(cmd[:2:2], cmd[3:]...))
+		}
+	}
+	Expect(names).To(Equal([]string{"GET", "SET", "GET", "SET"}))
+	// zero ttl stores value without expiration, otherwise ttl plus StaleTTL is rounded up to milliseconds
+	Expect(sets).To(Equal([][]string{
+		{"SET", "forever"},
+		{"SET", "short", "PX", "1002"},
+	}))
+
+	freshUntil, ok := parseEntry(cache.newEntry(entryValue, 0, nil))
+	Expect(ok).To(BeTrue())
+	Expect(freshUntil.IsZero()).To(BeTrue())
+}
+
+func TestCacheLoadTimeout(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	cache := NewCache(client, CacheOptions{LoadTimeout: 50 * time.Millisecond})
+
+	// loader ignoring ctx does not block its waiters
+	release := make(chan struct{})
+	defer close(release)
+	hung := func(ctx context.Context) (interface{}, error) {
+		<-release
+		return "late", nil
+	}
+
+	var v string
+	within(t, time.Second, func() {
+		err = cache.GetOrLoad(context.Background(), "hung", time.Minute, hung, &v)
+	})
+	Expect(err).To(Equal(context.DeadlineExceeded))
+
+	// the flight is over, so the next call starts a new loader
+	var left time.Duration
+	var hasDeadline bool
+	loader := func(ctx context.Context) (interface{}, error) {
+		var deadline time.Time
+		deadline, hasDeadline = ctx.Deadline()
+		left = time.Until(deadline)
+		return "value", nil
+	}
+	Expect(cache.GetOrLoad(context.Background(), "hung", time.Minute, loader, &v)).To(Succeed())
+	Expect(v).To(Equal("value"))
+	Expect(hasDeadline).To(BeTrue())
+	Expect(left).To(BeNumerically("<=", 50*time.Millisecond))
+}
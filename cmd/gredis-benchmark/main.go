@@ -0,0 +1,142 @@
+// Command gredis-benchmark generates load against a GRedis server and reports throughput and latency
+// percentiles for a configurable set of workloads.
+//
+// Usage:
+//
+//	gredis-benchmark [-url gredis://localhost] [-c 50] [-n 100000] [-P 1] [-r 10000] [-d 3] [-t set,get] [-json]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valery-barysok/gredis"
+)
+
+var (
+	rawURL   = flag.String("url", "gredis://localhost", "GRedis server URL")
+	clients  = flag.Int("c", 50, "number of parallel connections")
+	requests = flag.Int("n", 100000, "total number of requests per test")
+	pipeline = flag.Int("P", 1, "pipeline <numreq> requests")
+	keySpace = flag.Int("r", 10000, "number of distinct keys used by the tests")
+	dataSize = flag.Int("d", 3, "data size of SET/LPUSH/HSET values in bytes")
+	tests    = flag.String("t", "set,get,lpush,lpop,hset,hget", "comma separated list of tests to run")
+	mix      = flag.String("mix", "set=1,get=9", "weights of commands used by the \"mix\" test")
+	asJSON   = flag.Bool("json", false, "output results as JSON")
+)
+
+func main() {
+	flag.Parse()
+
+	opts, err := gredis.NewOptions(*rawURL)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *clients < 1 || *requests < 1 || *pipeline < 1 || *keySpace < 1 || *dataSize < 0 {
+		fatal(fmt.Errorf("-c, -n, -P and -r must be positive, -d must not be negative"))
+	}
+
+	value := strings.Repeat("x", *dataSize)
+
+	var results []*result
+	for _, name := range strings.Split(*tests, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+
+		w, err := newWorkload(name, *mix, value)
+		if err != nil {
+			fatal(err)
+		}
+
+		res, err := run(opts, w)
+		if err != nil {
+			fatal(err)
+		}
+
+		if !*asJSON {
+			res.print(os.Stdout)
+		}
+		results = append(results, res)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			fatal(err)
+		}
+	}
+}
+
+// run executes workload w with the configured number of clients and collects its latencies.
+func run(opts *gredis.Options, w *workload) (*result, error) {
+	conns := make([]*gredis.Client, 0, *clients)
+	defer func() {
+		for _, client := range conns {
+			client.Close()
+		}
+	}()
+
+	for i := 0; i < *clients; i++ {
+		client, err := gredis.Dial(opts)
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, client)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		latencies = make([]time.Duration, 0, *requests)
+		errCount  int
+		firstErr  error
+	)
+
+	perClient := *requests / *clients
+	extra := *requests % *clients
+
+	start := time.Now()
+	for i, client := range conns {
+		n := perClient
+		if i < extra {
+			n++
+		}
+
+		wg.Add(1)
+		go func(client *gredis.Client, n int, seed int64) {
+			defer wg.Done()
+
+			lat, errs, err := w.drive(client, n, *pipeline, seed)
+
+			mu.Lock()
+			latencies = append(latencies, lat...)
+			errCount += errs
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+		}(client, n, int64(i+1))
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return newResult(w.name, elapsed, latencies, errCount), nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "gredis-benchmark:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredis"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+func TestPercentile(t *testing.T) {
+	RegisterTestingT(t)
+
+	ten := make([]time.Duration, 10)
+	for i := range ten {
+		ten[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	cases := []struct {
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{nil, 50, 0},
+		{[]time.Duration{time.Second}, 0, time.Second},
+		{[]time.Duration{time.Second}, 99.9, time.Second},
+		{ten, 0, time.Millisecond},
+		{ten, 10, time.Millisecond},
+		{ten, 50, 5 * time.Millisecond},
+		{ten, 95, 10 * time.Millisecond},
+		{ten, 99, 10 * time.Millisecond},
+		{ten, 100, 10 * time.Millisecond},
+	}
+	for _, c := range cases {
+		Expect(percentile(c.sorted, c.p)).To(Equal(c.want), "p%v of %v", c.p, c.sorted)
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	RegisterTestingT(t)
+
+	latencies := []time.Duration{3 * time.Millisecond, time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond}
+	res := newResult("get", 2*time.Second, latencies, 1)
+
+	Expect(res.Requests).To(Equal(4))
+	Expect(res.Errors).To(Equal(1))
+	Expect(res.Throughput).To(Equal(2.0))
+	Expect(res.P50Ms).To(Equal(2.0))
+	Expect(res.MaxMs).To(Equal(4.0))
+}
+
+func TestNewWorkload(t *testing.T) {
+	RegisterTestingT(t)
+
+	valid := []struct {
+		name    string
+		mix     string
+		ops     int
+		total   int
+		display string
+	}{
+		{"set", "", 1, 1, "set"},
+		{"mix", "set=1,get=9", 2, 10, "mix(set=1,get=9)"},
+		{"mix", " SET=2 , hget=0 ,lpop=3", 3, 5, "mix( SET=2 , hget=0 ,lpop=3)"},
+	}
+	for _, c := range valid {
+		w, err := newWorkload(c.name, c.mix, "x")
+		Expect(err).ToNot(HaveOccurred(), c.mix)
+		Expect(w.ops).To(HaveLen(c.ops))
+		Expect(w.total).To(Equal(c.total))
+		Expect(w.name).To(Equal(c.display))
+	}
+
+	invalid := []struct {
+		name string
+		mix  string
+	}{
+		{"del", ""},
+		{"mix", "set"},
+		{"mix", "set=x"},
+		{"mix", "set=-1"},
+		{"mix", "del=1"},
+		{"mix", "set=0,get=0"},
+	}
+	for _, c := range invalid {
+		_, err := newWorkload(c.name, c.mix, "x")
+		Expect(err).To(HaveOccurred(), c.name+" "+c.mix)
+	}
+}
+
+func TestRun(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := gredis.NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	*clients, *requests, *pipeline = 3, 100, 8
+
+	for _, name := range []string{"set", "get", "mix"} {
+		w, err := newWorkload(name, "set=1,get=1,lpush=1,hset=1", "xyz")
+		Expect(err).ToNot(HaveOccurred())
+
+		res, err := run(opts, w)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Requests).To(Equal(100))
+		Expect(res.Errors).To(Equal(0))
+		Expect(res.Pipeline).To(Equal(8))
+		Expect(res.P50Ms).To(BeNumerically("<=", res.MaxMs))
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// result holds statistics of a single test run
+type result struct {
+	Test       string  `json:"test"`
+	Requests   int     `json:"requests"`
+	Errors     int     `json:"errors"`
+	Clients    int     `json:"clients"`
+	Pipeline   int     `json:"pipeline"`
+	DataSize   int     `json:"data_size"`
+	ElapsedMs  float64 `json:"elapsed_ms"`
+	Throughput float64 `json:"requests_per_second"`
+	P50Ms      float64 `json:"p50_ms"`
+	P95Ms      float64 `json:"p95_ms"`
+	P99Ms      float64 `json:"p99_ms"`
+	P999Ms     float64 `json:"p999_ms"`
+	MaxMs      float64 `json:"max_ms"`
+}
+
+func newResult(test string, elapsed time.Duration, latencies []time.Duration, errs int) *result {
+	sort.Sort(durations(latencies))
+
+	res := &result{
+		Test:      test,
+		Requests:  len(latencies),
+		Errors:    errs,
+		Clients:   *clients,
+		Pipeline:  *pipeline,
+		DataSize:  *dataSize,
+		ElapsedMs: ms(elapsed),
+		P50Ms:     ms(percentile(latencies, 50)),
+		P95Ms:     ms(percentile(latencies, 95)),
+		P99Ms:     ms(percentile(latencies, 99)),
+		P999Ms:    ms(percentile(latencies, 99.9)),
+	}
+
+	if len(latencies) > 0 {
+		res.MaxMs = ms(latencies[len(latencies)-1])
+	}
+	if elapsed > 0 {
+		res.Throughput = float64(len(latencies)) / elapsed.Seconds()
+	}
+
+	return res
+}
+
+func (res *result) print(w io.Writer) {
+	fmt.Fprintf(w, "====== %s ======\n", res.Test)
+	fmt.Fprintf(w, "  %d requests completed in %.3f seconds\n", res.Requests, res.ElapsedMs/1000)
+	fmt.Fprintf(w, "  %d parallel clients, pipeline %d, %d bytes payload\n", res.Clients, res.Pipeline, res.DataSize)
+	if res.Errors > 0 {
+		fmt.Fprintf(w, "  %d error replies\n", res.Errors)
+	}
+	fmt.Fprintf(w, "\n  %-12s %10s\n", "throughput", fmt.Sprintf("%.2f/s", res.Throughput))
+	fmt.Fprintf(w, "  %-12s %10.3f ms\n", "p50", res.P50Ms)
+	fmt.Fprintf(w, "  %-12s %10.3f ms\n", "p95", res.P95Ms)
+	fmt.Fprintf(w, "  %-12s %10.3f ms\n", "p99", res.P99Ms)
+	fmt.Fprintf(w, "  %-12s %10.3f ms\n", "p99.9", res.P999Ms)
+	fmt.Fprintf(w, "  %-12s %10.3f ms\n\n", "max", res.MaxMs)
+}
+
+// percentile returns p-th percentile of sorted latencies using the nearest-rank method
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+
+	return sorted[rank]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valery-barysok/gredis"
+)
+
+// op builds a single command of a workload
+type op func(r *rand.Rand) (cmd []byte, args [][]byte)
+
+// workload is a weighted set of commands
+type workload struct {
+	name    string
+	ops     []op
+	weights []int
+	total   int
+}
+
+func randKey(r *rand.Rand, prefix string) []byte {
+	return []byte(prefix + strconv.Itoa(r.Intn(*keySpace)))
+}
+
+func newOp(name string, value string) (op, error) {
+	v := []byte(value)
+
+	switch name {
+	case "set":
+		return func(r *rand.Rand) ([]byte, [][]byte) {
+			return gredis.SetCommand, [][]byte{randKey(r, "key:"), v}
+		}, nil
+	case "get":
+		return func(r *rand.Rand) ([]byte, [][]byte) {
+			return gredis.GetCommand, [][]byte{randKey(r, "key:")}
+		}, nil
+	case "lpush":
+		return func(r *rand.Rand) ([]byte, [][]byte) {
+			return gredis.LPushCommand, [][]byte{randKey(r, "list:"), v}
+		}, nil
+	case "lpop":
+		return func(r *rand.Rand) ([]byte, [][]byte) {
+			return gredis.LPopCommand, [][]byte{randKey(r, "list:")}
+		}, nil
+	case "hset":
+		return func(r *rand.Rand) ([]byte, [][]byte) {
+			return gredis.HSetCommand, [][]byte{randKey(r, "hash:"), randKey(r, "field:"), v}
+		}, nil
+	case "hget":
+		return func(r *rand.Rand) ([]byte, [][]byte) {
+			return gredis.HGetCommand, [][]byte{randKey(r, "hash:"), randKey(r, "field:")}
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown test: %s", name)
+}
+
+// newWorkload returns workload for test name. The "mix" test is built from weights given in
+// "cmd=weight,cmd=weight" form.
+func newWorkload(name string, mix string, value string) (*workload, error) {
+	w := &workload{name: name}
+
+	if name != "mix" {
+		o, err := newOp(name, value)
+		if err != nil {
+			return nil, err
+		}
+		w.add(o, 1)
+		return w, nil
+	}
+
+	for _, part := range strings.Split(mix, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid mix entry: %s", part)
+		}
+
+		weight, err := strconv.Atoi(kv[1])
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid mix weight: %s", part)
+		}
+
+		o, err := newOp(strings.ToLower(kv[0]), value)
+		if err != nil {
+			return nil, err
+		}
+		w.add(o, weight)
+	}
+
+	if w.total == 0 {
+		return nil, fmt.Errorf("invalid mix: %s", mix)
+	}
+	w.name = "mix(" + mix + ")"
+
+	return w, nil
+}
+
+func (w *workload) add(o op, weight int) {
+	w.ops = append(w.ops, o)
+	w.weights = append(w.weights, weight)
+	w.total += weight
+}
+
+func (w *workload) pick(r *rand.Rand) op {
+	n := r.Intn(w.total)
+	for i, weight := range w.weights {
+		if n < weight {
+			return w.ops[i]
+		}
+		n -= weight
+	}
+
+	return w.ops[len(w.ops)-1]
+}
+
+// drive sends n commands through client in pipelines of depth commands, so every pipeline takes a single
+// round-trip. Every command of a pipeline is accounted with the latency of the whole pipeline. Error replies
+// are counted, network errors abort the run.
+func (w *workload) drive(client *gredis.Client, n int, depth int, seed int64) ([]time.Duration, int, error) {
+	r := rand.New(rand.NewSource(seed))
+	latencies := make([]time.Duration, 0, n)
+	errs := 0
+	cmds := make([]*gredis.Cmd, 0, depth)
+
+	for n > 0 {
+		batch := depth
+		if batch > n {
+			batch = n
+		}
+
+		cmds = cmds[:0]
+		for i := 0; i < batch; i++ {
+			cmd, args := w.pick(r)(r)
+			cmds = append(cmds, gredis.NewCmd(cmd, args...))
+		}
+
+		start := time.Now()
+		client.Pipeline(cmds...)
+		for _, cmd := range cmds {
+			if cmd.Err != nil {
+				if isNetError(cmd.Err) {
+					return latencies, errs, cmd.Err
+				}
+				errs++
+			}
+		}
+
+		elapsed := time.Since(start)
+		for i := 0; i < batch; i++ {
+			latencies = append(latencies, elapsed)
+		}
+
+		n -= batch
+	}
+
+	return latencies, errs, nil
+}
+
+func isNetError(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	_, ok := err.(net.Error)
+	return ok
+}
@@ -0,0 +1,116 @@
+// Command gredis-dump writes keys of a GRedis database to a portable file and restores them back.
+//
+// Usage:
+//
+//	gredis-dump [-url gredis://localhost/0] [-pattern .*] [-format json|binary] [-o dump.jsonl]
+//	gredis-dump -restore [-url gredis://localhost/1] [-format json|binary] [-i dump.jsonl]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/valery-barysok/gredis"
+)
+
+var (
+	rawURL  = flag.String("url", "gredis://localhost", "GRedis server URL")
+	pattern = flag.String("pattern", ".*", "regexp pattern of keys to dump")
+	format  = flag.String("format", "json", "dump format: json or binary")
+	output  = flag.String("o", "", "output file, stdout by default")
+	input   = flag.String("i", "", "input file used by -restore, stdin by default")
+	restore = flag.Bool("restore", false, "restore dump instead of creating it")
+)
+
+func main() {
+	flag.Parse()
+
+	if *format != "json" && *format != "binary" {
+		fatal(fmt.Errorf("unknown format: %s", *format))
+	}
+
+	opts, err := gredis.NewOptions(*rawURL)
+	if err != nil {
+		fatal(err)
+	}
+
+	client, err := gredis.Dial(opts)
+	if err != nil {
+		fatal(err)
+	}
+	defer client.Close()
+
+	var cnt int
+	if *restore {
+		cnt, err = restoreDump(client)
+	} else {
+		cnt, err = dump(client)
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "gredis-dump: %d keys processed\n", cnt)
+}
+
+func dump(client *gredis.Client) (cnt int, err error) {
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return 0, err
+		}
+		defer func() {
+			// written data may reach the disk only on close
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		out = f
+	}
+
+	bw := bufio.NewWriter(out)
+
+	var w gredis.DumpWriter
+	if *format == "binary" {
+		w = gredis.NewBinaryDumpWriter(bw)
+	} else {
+		w = gredis.NewJSONDumpWriter(bw)
+	}
+
+	cnt, err = gredis.Dump(client, *pattern, w)
+	if err != nil {
+		return cnt, err
+	}
+
+	return cnt, bw.Flush()
+}
+
+func restoreDump(client *gredis.Client) (int, error) {
+	var in io.Reader = os.Stdin
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var r gredis.DumpReader
+	if *format == "binary" {
+		r = gredis.NewBinaryDumpReader(in)
+	} else {
+		r = gredis.NewJSONDumpReader(bufio.NewReader(in))
+	}
+
+	return gredis.Restore(client, r)
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "gredis-dump:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,290 @@
+package gredis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+)
+
+// ErrNil is returned by typed helpers when key or field does not exist
+var ErrNil = errors.New("nil reply")
+
+var errInvalidSlicePtr = errors.New("value must be a non-nil pointer to slice")
+
+// Codec encodes values stored in GRedis server and decodes them back
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec encodes values as JSON. It is used when Options.Codec is not set.
+type JSONCodec struct{}
+
+// Marshal returns JSON encoding of v
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON data into v
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec encodes values with encoding/gob
+type GobCodec struct{}
+
+// Marshal returns gob encoding of v
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes gob data into v
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// RawCodec stores []byte, string and types implementing encoding.BinaryMarshaler as is. Values are decoded
+// into *[]byte, *string and types implementing encoding.BinaryUnmarshaler.
+type RawCodec struct{}
+
+// Marshal returns bytes of v
+func (RawCodec) Marshal(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	}
+
+	return nil, fmt.Errorf("raw codec does not support %T", v)
+}
+
+// Unmarshal stores data into v
+func (RawCodec) Unmarshal(data []byte, v interface{}) error {
+	switch v := v.(type) {
+	case *[]byte:
+		*v = append([]byte(nil), data...)
+		return nil
+	case *string:
+		*v = string(data)
+		return nil
+	case encoding.BinaryUnmarshaler:
+		return v.UnmarshalBinary(data)
+	}
+
+	return fmt.Errorf("raw codec does not support %T", v)
+}
+
+const (
+	plainMarker byte = iota
+	gzipMarker
+)
+
+type compressCodec struct {
+	codec     Codec
+	threshold int
+}
+
+// NewCompressCodec returns Codec compressing values encoded by codec with gzip when they are longer than
+// threshold bytes. Every value is prefixed with a marker byte, so values of different sizes can be decoded
+// transparently.
+func NewCompressCodec(codec Codec, threshold int) Codec {
+	return &compressCodec{codec: codec, threshold: threshold}
+}
+
+func (c *compressCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) <= c.threshold {
+		return append([]byte{plainMarker}, data...), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(gzipMarker)
+
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *compressCodec) Unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return errors.New("compressed value has no marker")
+	}
+
+	switch data[0] {
+	case plainMarker:
+		return c.codec.Unmarshal(data[1:], v)
+	case gzipMarker:
+		zr, err := gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+
+		plain, err := ioutil.ReadAll(zr)
+		if err != nil {
+			return err
+		}
+
+		return c.codec.Unmarshal(plain, v)
+	}
+
+	return fmt.Errorf("unknown compression marker: %d", data[0])
+}
+
+func (client *Client) codec() Codec {
+	if client.opts.Codec != nil {
+		return client.opts.Codec
+	}
+
+	return JSONCodec{}
+}
+
+// marshal encodes values with configured codec and adds them to a
+func (client *Client) marshal(a *cmdArgs, values ...interface{}) error {
+	codec := client.codec()
+
+	for _, v := range values {
+		data, err := codec.Marshal(v)
+		if err != nil {
+			return err
+		}
+		a.addBytes(data)
+	}
+
+	return nil
+}
+
+// SetValue encodes v with configured codec and sets key to hold it, see Set.
+func (client *Client) SetValue(key string, v interface{}) (bool, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	if err := client.marshal(a.addString(key), v); err != nil {
+		return false, err
+	}
+
+	if _, err := client.Do(SetCommand, a.bulks()...); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetValue decodes the value of key into v with configured codec. ErrNil is returned if key does not exist.
+func (client *Client) GetValue(key string, v interface{}) error {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(GetCommand, a.addString(key).bulks()...)
+	if err != nil {
+		return err
+	}
+
+	if msg.IsNil() {
+		return ErrNil
+	}
+
+	return client.codec().Unmarshal(msg.BulkString(), v)
+}
+
+// LPushValue encodes values with configured codec and inserts them at the head of the list stored at key,
+// see LPush.
+func (client *Client) LPushValue(key string, v interface{}, values ...interface{}) (int, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	if err := client.marshal(a.addString(key), v); err != nil {
+		return 0, err
+	}
+	if err := client.marshal(a, values...); err != nil {
+		return 0, err
+	}
+
+	msg, err := client.Do(LPushCommand, a.bulks()...)
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.Int(), nil
+}
+
+// LRangeValues decodes the specified elements of the list stored at key with configured codec and stores them
+// into slice pointed by values, see LRange.
+func (client *Client) LRangeValues(key string, start int, stop int, values interface{}) error {
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return errInvalidSlicePtr
+	}
+
+	items, err := client.LRange(key, start, stop)
+	if err != nil {
+		return err
+	}
+
+	codec := client.codec()
+	slice := reflect.MakeSlice(rv.Elem().Type(), len(items), len(items))
+	for i, item := range items {
+		if err := codec.Unmarshal(item, slice.Index(i).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	rv.Elem().Set(slice)
+
+	return nil
+}
+
+// HSetValue encodes v with configured codec and sets field in the hash stored at key to it, see HSet.
+func (client *Client) HSetValue(key string, field string, v interface{}) (int, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	if err := client.marshal(a.addString(key).addString(field), v); err != nil {
+		return 0, err
+	}
+
+	msg, err := client.Do(HSetCommand, a.bulks()...)
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.Int(), nil
+}
+
+// HGetValue decodes the value associated with field in the hash stored at key into v with configured codec.
+// ErrNil is returned if field or key does not exist.
+func (client *Client) HGetValue(key string, field string, v interface{}) error {
+	data, err := client.HGet(key, field)
+	if err != nil {
+		return err
+	}
+
+	if data == nil {
+		return ErrNil
+	}
+
+	return client.codec().Unmarshal(data, v)
+}
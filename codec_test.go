@@ -0,0 +1,128 @@
+package gredis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+type codecRecord struct {
+	Name  string
+	Count int
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	RegisterTestingT(t)
+
+	codecs := map[string]Codec{
+		"json":          JSONCodec{},
+		"gob":           GobCodec{},
+		"compress-json": NewCompressCodec(JSONCodec{}, 16),
+		"compress-gob":  NewCompressCodec(GobCodec{}, 0),
+	}
+
+	small := codecRecord{Name: "a", Count: 1}
+	large := codecRecord{Name: strings.Repeat("a", 1000), Count: 2}
+
+	for name, codec := range codecs {
+		for _, rec := range []codecRecord{small, large} {
+			data, err := codec.Marshal(&rec)
+			Expect(err).ToNot(HaveOccurred(), name)
+
+			var res codecRecord
+			Expect(codec.Unmarshal(data, &res)).To(Succeed(), name)
+			Expect(res).To(Equal(rec), name)
+		}
+	}
+}
+
+func TestCompressCodec(t *testing.T) {
+	RegisterTestingT(t)
+
+	codec := NewCompressCodec(RawCodec{}, 16)
+
+	data, err := codec.Marshal("short")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(data).To(Equal(append([]byte{plainMarker}, "short"...)))
+
+	long := strings.Repeat("x", 1000)
+	data, err = codec.Marshal(long)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(data[0]).To(Equal(gzipMarker))
+	Expect(len(data)).To(BeNumerically("<", len(long)))
+
+	var res string
+	Expect(codec.Unmarshal(data, &res)).To(Succeed())
+	Expect(res).To(Equal(long))
+
+	Expect(codec.Unmarshal([]byte{42}, &res)).To(MatchError("unknown compression marker: 42"))
+}
+
+func TestRawCodec(t *testing.T) {
+	RegisterTestingT(t)
+
+	codec := RawCodec{}
+
+	now := time.Date(2017, 5, 1, 10, 20, 30, 0, time.UTC)
+	data, err := codec.Marshal(now)
+	Expect(err).ToNot(HaveOccurred())
+
+	var tm time.Time
+	Expect(codec.Unmarshal(data, &tm)).To(Succeed())
+	Expect(tm.Equal(now)).To(BeTrue())
+
+	var b []byte
+	Expect(codec.Unmarshal([]byte("bytes"), &b)).To(Succeed())
+	Expect(b).To(Equal([]byte("bytes")))
+
+	_, err = codec.Marshal(42)
+	Expect(err).To(MatchError("raw codec does not support int"))
+}
+
+func TestTypedValues(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+	opts.Codec = NewCompressCodec(GobCodec{}, 64)
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	rec := codecRecord{Name: "name", Count: 42}
+
+	_, err = client.SetValue("key", rec)
+	Expect(err).ToNot(HaveOccurred())
+
+	var res codecRecord
+	Expect(client.GetValue("key", &res)).To(Succeed())
+	Expect(res).To(Equal(rec))
+
+	Expect(client.GetValue("missing", &res)).To(Equal(ErrNil))
+
+	cnt, err := client.LPushValue("list", codecRecord{Count: 1}, codecRecord{Count: 2})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(cnt).To(Equal(2))
+
+	var list []codecRecord
+	Expect(client.LRangeValues("list", 0, -1, &list)).To(Succeed())
+	Expect(list).To(Equal([]codecRecord{{Count: 2}, {Count: 1}}))
+
+	_, err = client.HSetValue("hash", "field", rec)
+	Expect(err).ToNot(HaveOccurred())
+
+	res = codecRecord{}
+	Expect(client.HGetValue("hash", "field", &res)).To(Succeed())
+	Expect(res).To(Equal(rec))
+
+	Expect(client.HGetValue("hash", "missing", &res)).To(Equal(ErrNil))
+}
@@ -0,0 +1,474 @@
+package gredis
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DumpVersion is the version of dump formats written by this package
+const DumpVersion = 1
+
+// Value types of dump records. They match replies of `TYPE` command.
+const (
+	TypeString = "string"
+	TypeList   = "list"
+	TypeHash   = "hash"
+	TypeNone   = "none"
+)
+
+const dumpFormatName = "gredis-dump"
+
+var dumpBinaryMagic = []byte("GRDUMP")
+
+var errInvalidDump = errors.New("invalid dump format")
+
+const (
+	// maxDumpLen limits length of keys, values and number of elements read from binary dump, it matches
+	// the maximum length of bulk string of the server
+	maxDumpLen = 512 << 20
+	// dumpChunkLen is the size of chunks longer values are read by, so corrupt length does not allocate
+	// more memory than the dump actually holds
+	dumpChunkLen = 64 << 10
+)
+
+// DumpRecord is a snapshot of a single key
+type DumpRecord struct {
+	Key   string            `json:"key"`
+	Type  string            `json:"type"`
+	TTL   int               `json:"ttl,omitempty"`
+	Value []byte            `json:"value,omitempty"`
+	List  [][]byte          `json:"list,omitempty"`
+	Hash  map[string][]byte `json:"hash,omitempty"`
+}
+
+// DumpWriter writes dump records to underlying stream
+type DumpWriter interface {
+	WriteRecord(rec *DumpRecord) error
+}
+
+// DumpReader reads dump records from underlying stream. ReadRecord returns io.EOF when there are no more
+// records.
+type DumpReader interface {
+	ReadRecord() (*DumpRecord, error)
+}
+
+// Dump walks all keys matching **regexp** pattern and writes them with their TTLs to w one by one. Keys are
+// streamed with KeysEach, so the list of keys is never held in memory. Keys removed while the dump is in
+// progress are skipped.
+//
+// Returns the number of dumped keys.
+func Dump(client *Client, pattern string, w DumpWriter) (int, error) {
+	cnt := 0
+	err := client.KeysEach(pattern, func(key []byte) error {
+		rec, err := dumpKey(client, string(key))
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			return nil
+		}
+
+		if err := w.WriteRecord(rec); err != nil {
+			return err
+		}
+		cnt++
+
+		return nil
+	})
+
+	return cnt, err
+}
+
+func dumpKey(client *Client, key string) (*DumpRecord, error) {
+	typ, err := client.Type(key)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &DumpRecord{Key: key, Type: typ}
+
+	switch typ {
+	case TypeNone:
+		return nil, nil
+	case TypeString:
+		rec.Value, err = client.Get(key)
+	case TypeList:
+		rec.List, err = client.LRange(key, 0, -1)
+	case TypeHash:
+		rec.Hash, err = dumpHash(client, key)
+	default:
+		return nil, fmt.Errorf("unsupported type %s of key %s", typ, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := client.TTL(key)
+	if err != nil {
+		return nil, err
+	}
+	if ttl == -2 {
+		return nil, nil
+	}
+	if ttl > 0 {
+		rec.TTL = ttl
+	}
+
+	return rec, nil
+}
+
+func dumpHash(client *Client, key string) (map[string][]byte, error) {
+	fields, err := client.HKeys(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string][]byte, len(fields))
+	for _, field := range fields {
+		value, err := client.HGet(key, string(field))
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			res[string(field)] = value
+		}
+	}
+
+	return res, nil
+}
+
+// Restore reads records from r until io.EOF and writes them to GRedis server. Existing keys are replaced.
+//
+// Returns the number of restored keys.
+func Restore(client *Client, r DumpReader) (int, error) {
+	cnt := 0
+	for {
+		rec, err := r.ReadRecord()
+		if err == io.EOF {
+			return cnt, nil
+		}
+		if err != nil {
+			return cnt, err
+		}
+
+		if err := restoreKey(client, rec); err != nil {
+			return cnt, err
+		}
+		cnt++
+	}
+}
+
+func restoreKey(client *Client, rec *DumpRecord) error {
+	if _, err := client.Del(rec.Key); err != nil {
+		return err
+	}
+
+	switch rec.Type {
+	case TypeString:
+		if _, err := client.Set(rec.Key, string(rec.Value)); err != nil {
+			return err
+		}
+	case TypeList:
+		if len(rec.List) == 0 {
+			return nil
+		}
+
+		values := make([]string, 0, len(rec.List)-1)
+		for _, value := range rec.List[1:] {
+			values = append(values, string(value))
+		}
+
+		if _, err := client.RPush(rec.Key, string(rec.List[0]), values...); err != nil {
+			return err
+		}
+	case TypeHash:
+		for field, value := range rec.Hash {
+			if _, err := client.HSet(rec.Key, field, string(value)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %s of key %s", rec.Type, rec.Key)
+	}
+
+	if rec.TTL > 0 {
+		if _, err := client.Expire(rec.Key, rec.TTL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type dumpHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+type jsonDumpWriter struct {
+	enc    *json.Encoder
+	header bool
+}
+
+// NewJSONDumpWriter returns DumpWriter writing versioned JSON-lines format. The first line is a header,
+// every next line is a single record.
+func NewJSONDumpWriter(w io.Writer) DumpWriter {
+	return &jsonDumpWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *jsonDumpWriter) WriteRecord(rec *DumpRecord) error {
+	if !w.header {
+		if err := w.enc.Encode(&dumpHeader{Format: dumpFormatName, Version: DumpVersion}); err != nil {
+			return err
+		}
+		w.header = true
+	}
+
+	return w.enc.Encode(rec)
+}
+
+type jsonDumpReader struct {
+	dec    *json.Decoder
+	header bool
+}
+
+// NewJSONDumpReader returns DumpReader of format written by NewJSONDumpWriter
+func NewJSONDumpReader(r io.Reader) DumpReader {
+	return &jsonDumpReader{dec: json.NewDecoder(r)}
+}
+
+func (r *jsonDumpReader) ReadRecord() (*DumpRecord, error) {
+	if !r.header {
+		var header dumpHeader
+		if err := r.dec.Decode(&header); err != nil {
+			return nil, err
+		}
+		if header.Format != dumpFormatName {
+			return nil, errInvalidDump
+		}
+		if header.Version != DumpVersion {
+			return nil, fmt.Errorf("unsupported dump version: %d", header.Version)
+		}
+		r.header = true
+	}
+
+	var rec DumpRecord
+	if err := r.dec.Decode(&rec); err != nil {
+		return nil, err
+	}
+
+	return &rec, nil
+}
+
+var dumpTypes = []string{TypeString, TypeList, TypeHash}
+
+type binaryDumpWriter struct {
+	w      io.Writer
+	buf    []byte
+	header bool
+}
+
+// NewBinaryDumpWriter returns DumpWriter writing compact binary format. Records are written directly to w,
+// so wrap w into bufio.Writer and flush it when done if w is unbuffered.
+func NewBinaryDumpWriter(w io.Writer) DumpWriter {
+	return &binaryDumpWriter{w: w}
+}
+
+func (w *binaryDumpWriter) WriteRecord(rec *DumpRecord) error {
+	typ := -1
+	for i, t := range dumpTypes {
+		if t == rec.Type {
+			typ = i
+		}
+	}
+	if typ < 0 {
+		return fmt.Errorf("unsupported type %s of key %s", rec.Type, rec.Key)
+	}
+
+	buf := w.buf[:0]
+
+	if !w.header {
+		buf = append(buf, dumpBinaryMagic...)
+		buf = appendUvarint(buf, DumpVersion)
+		w.header = true
+	}
+
+	buf = append(buf, byte(typ))
+	buf = appendBytes(buf, []byte(rec.Key))
+	buf = appendUvarint(buf, uint64(rec.TTL))
+
+	switch rec.Type {
+	case TypeString:
+		buf = appendBytes(buf, rec.Value)
+	case TypeList:
+		buf = appendUvarint(buf, uint64(len(rec.List)))
+		for _, value := range rec.List {
+			buf = appendBytes(buf, value)
+		}
+	case TypeHash:
+		buf = appendUvarint(buf, uint64(len(rec.Hash)))
+		for field, value := range rec.Hash {
+			buf = appendBytes(buf, []byte(field))
+			buf = appendBytes(buf, value)
+		}
+	}
+
+	w.buf = buf
+	_, err := w.w.Write(buf)
+	return err
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+func appendBytes(buf []byte, value []byte) []byte {
+	buf = appendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+type binaryDumpReader struct {
+	r      *bufio.Reader
+	header bool
+}
+
+// NewBinaryDumpReader returns DumpReader of format written by NewBinaryDumpWriter
+func NewBinaryDumpReader(r io.Reader) DumpReader {
+	return &binaryDumpReader{r: bufio.NewReader(r)}
+}
+
+func (r *binaryDumpReader) ReadRecord() (*DumpRecord, error) {
+	if !r.header {
+		magic := make([]byte, len(dumpBinaryMagic))
+		if _, err := io.ReadFull(r.r, magic); err != nil {
+			return nil, err
+		}
+		if string(magic) != string(dumpBinaryMagic) {
+			return nil, errInvalidDump
+		}
+
+		version, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if version != DumpVersion {
+			return nil, fmt.Errorf("unsupported dump version: %d", version)
+		}
+		r.header = true
+	}
+
+	typ, err := r.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if int(typ) >= len(dumpTypes) {
+		return nil, errInvalidDump
+	}
+
+	rec := &DumpRecord{Type: dumpTypes[typ]}
+
+	key, err := r.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	rec.Key = string(key)
+
+	ttl, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	rec.TTL = int(ttl)
+
+	switch rec.Type {
+	case TypeString:
+		rec.Value, err = r.readBytes()
+		if err != nil {
+			return nil, err
+		}
+	case TypeList:
+		n, err := r.readLen()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 0; i < n; i++ {
+			value, err := r.readBytes()
+			if err != nil {
+				return nil, err
+			}
+			rec.List = append(rec.List, value)
+		}
+	case TypeHash:
+		n, err := r.readLen()
+		if err != nil {
+			return nil, err
+		}
+
+		rec.Hash = make(map[string][]byte)
+		for i := 0; i < n; i++ {
+			field, err := r.readBytes()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := r.readBytes()
+			if err != nil {
+				return nil, err
+			}
+			rec.Hash[string(field)] = value
+		}
+	}
+
+	return rec, nil
+}
+
+// readLen reads length of value or number of elements, lengths above maxDumpLen are rejected
+func (r *binaryDumpReader) readLen() (int, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if n > maxDumpLen {
+		return 0, errInvalidDump
+	}
+
+	return int(n), nil
+}
+
+func (r *binaryDumpReader) readBytes() ([]byte, error) {
+	n, err := r.readLen()
+	if err != nil {
+		return nil, err
+	}
+
+	if n <= dumpChunkLen {
+		res := make([]byte, n)
+		if _, err := io.ReadFull(r.r, res); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		return res, nil
+	}
+
+	// buffer grows only as data arrives
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r.r, int64(n)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
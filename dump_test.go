@@ -0,0 +1,190 @@
+package gredis
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+var dumpRecords = []*DumpRecord{
+	{Key: "string_key", Type: TypeString, Value: []byte("value\x00\xff")},
+	{Key: "list_key", Type: TypeList, TTL: 100, List: [][]byte{[]byte("a"), []byte("b"), []byte("c")}},
+	{Key: "hash_key", Type: TypeHash, Hash: map[string][]byte{"f1": []byte("v1"), "f2": []byte("v2")}},
+}
+
+func TestDumpFormatsRoundTrip(t *testing.T) {
+	RegisterTestingT(t)
+
+	formats := []struct {
+		name string
+		w    func(w io.Writer) DumpWriter
+		r    func(r io.Reader) DumpReader
+	}{
+		{"json", NewJSONDumpWriter, NewJSONDumpReader},
+		{"binary", NewBinaryDumpWriter, NewBinaryDumpReader},
+	}
+
+	for _, f := range formats {
+		var buf bytes.Buffer
+
+		w := f.w(&buf)
+		for _, rec := range dumpRecords {
+			Expect(w.WriteRecord(rec)).To(Succeed(), f.name)
+		}
+
+		r := f.r(&buf)
+		for _, rec := range dumpRecords {
+			res, err := r.ReadRecord()
+			if Expect(err).ToNot(HaveOccurred(), f.name) {
+				Expect(res).To(Equal(rec), f.name)
+			}
+		}
+
+		_, err := r.ReadRecord()
+		Expect(err).To(Equal(io.EOF), f.name)
+	}
+}
+
+func TestDumpInvalidFormat(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, err := NewJSONDumpReader(bytes.NewBufferString(`{"format":"other","version":1}`)).ReadRecord()
+	Expect(err).To(MatchError("invalid dump format"))
+
+	_, err = NewJSONDumpReader(bytes.NewBufferString(`{"format":"gredis-dump","version":2}`)).ReadRecord()
+	Expect(err).To(MatchError("unsupported dump version: 2"))
+
+	_, err = NewBinaryDumpReader(bytes.NewBufferString("NOTDUMP")).ReadRecord()
+	Expect(err).To(MatchError("invalid dump format"))
+
+	err = NewBinaryDumpWriter(&bytes.Buffer{}).WriteRecord(&DumpRecord{Key: "key", Type: "set"})
+	Expect(err).To(MatchError("unsupported type set of key key"))
+}
+
+func TestBinaryDumpCorrupt(t *testing.T) {
+	RegisterTestingT(t)
+
+	header := appendUvarint(append([]byte(nil), dumpBinaryMagic...), DumpVersion)
+	key := appendBytes(nil, []byte("key"))
+
+	cases := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"huge key", appendUvarint(append(header, 0), 1<<62), errInvalidDump},
+		{"huge value", appendUvarint(appendUvarint(append(append(header, 0), key...), 0), 1<<62), errInvalidDump},
+		{"huge list", appendUvarint(appendUvarint(append(append(header, 1), key...), 0), 1<<40), errInvalidDump},
+		{"huge hash", appendUvarint(appendUvarint(append(append(header, 2), key...), 0), 1<<40), errInvalidDump},
+		{"truncated value", appendUvarint(appendUvarint(append(append(header, 0), key...), 0), maxDumpLen),
+			io.ErrUnexpectedEOF},
+		{"truncated list", appendUvarint(appendUvarint(append(append(header, 1), key...), 0), maxDumpLen),
+			io.ErrUnexpectedEOF},
+	}
+
+	for _, c := range cases {
+		_, err := NewBinaryDumpReader(bytes.NewReader(c.data)).ReadRecord()
+		Expect(err).To(Equal(c.err), c.name)
+	}
+}
+
+func TestDumpAndRestore(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost/1")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	_, err = client.Set("string_key", "value")
+	Expect(err).ToNot(HaveOccurred())
+	_, err = client.RPush("list_key", "a", "b", "c")
+	Expect(err).ToNot(HaveOccurred())
+	_, err = client.Expire("list_key", 100)
+	Expect(err).ToNot(HaveOccurred())
+	_, err = client.HSet("hash_key", "f1", "v1")
+	Expect(err).ToNot(HaveOccurred())
+	_, err = client.HSet("hash_key", "f2", "v2")
+	Expect(err).ToNot(HaveOccurred())
+
+	var buf bytes.Buffer
+	cnt, err := Dump(client, ".*", NewJSONDumpWriter(&buf))
+	Expect(err).ToNot(HaveOccurred())
+	Expect(cnt).To(Equal(3))
+
+	_, err = client.Select(2)
+	Expect(err).ToNot(HaveOccurred())
+
+	cnt, err = Restore(client, NewJSONDumpReader(&buf))
+	Expect(err).ToNot(HaveOccurred())
+	Expect(cnt).To(Equal(3))
+
+	value, err := client.Get("string_key")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(value).To(BeEquivalentTo("value"))
+
+	values, err := client.LRange("list_key", 0, -1)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(values).To(Equal([][]byte{[]byte("a"), []byte("b"), []byte("c")}))
+
+	ttl, err := client.TTL("list_key")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(ttl).To(BeNumerically(">", 0))
+
+	typ, err := client.Type("hash_key")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(typ).To(Equal(TypeHash))
+
+	value, err = client.HGet("hash_key", "f2")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(value).To(BeEquivalentTo("v2"))
+}
+
+func TestDumpStreamsKeys(t *testing.T) {
+	RegisterTestingT(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+	defer l.Close()
+
+	// every reply except KEYS is +OK, so TYPE of the first key is unsupported
+	s := &keysServer{l: l, keys: []string{"a", "b"}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+
+	opts, err := NewOptions("gredis://" + l.Addr().String())
+	Expect(err).ToNot(HaveOccurred())
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	hook := &recordHook{}
+	client.AddHook(hook)
+
+	var cnt int
+	within(t, 5*time.Second, func() {
+		cnt, err = Dump(client, ".*", NewJSONDumpWriter(&bytes.Buffer{}))
+	})
+	Expect(err).To(MatchError("unsupported type OK of key a"))
+	Expect(cnt).To(Equal(0))
+	Expect(hook.cmds).To(Equal([]string{"TYPE", "KEYS"}))
+}
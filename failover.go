@@ -0,0 +1,56 @@
+package gredis
+
+import "time"
+
+// Addr returns address of GRedis server the client is currently connected to
+func (client *Client) Addr() string {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	return client.addr
+}
+
+// monitor pings current server every interval and fails over to the next one when it does not reply
+func (client *Client) monitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-client.done:
+			return
+		case <-ticker.C:
+		}
+
+		ping := NewCmd(PingCommand)
+		ping.internal = true
+		if err := client.process(ping); err == nil {
+			continue
+		}
+
+		client.failover()
+	}
+}
+
+// failover connects to the first reachable server following the current one in the list of addresses
+func (client *Client) failover() {
+	for i := 1; i <= len(client.addrs); i++ {
+		idx := (client.addrIdx + i) % len(client.addrs)
+		addr := client.addrs[idx]
+
+		if err := client.connect(addr); err != nil {
+			if err == ErrClosed {
+				return
+			}
+			continue
+		}
+
+		client.addrIdx = idx
+		client.stats.reconnected()
+
+		if client.opts.OnFailover != nil {
+			client.opts.OnFailover(addr)
+		}
+		return
+	}
+}
@@ -0,0 +1,152 @@
+package gredis
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+// pongServer replies with PONG to every PING until closed
+type pongServer struct {
+	l     net.Listener
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newPongServer() (*pongServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &pongServer{l: l}
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *pongServer) serve() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+
+		go func() {
+			buf := make([]byte, 1024)
+			for {
+				n, err := conn.Read(buf)
+				if err != nil {
+					return
+				}
+				for i := 0; i < bytes.Count(buf[:n], []byte("PING")); i++ {
+					conn.Write([]byte("+PONG\r\n"))
+				}
+			}
+		}()
+	}
+}
+
+func (s *pongServer) Close() {
+	s.l.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func TestDialSkipsUnreachableHosts(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost:1,localhost:16379")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	if Expect(err).ToNot(HaveOccurred()) {
+		Expect(client.Addr()).To(Equal("localhost:16379"))
+		client.Close()
+	}
+}
+
+func TestFailover(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	s, err := newPongServer()
+	Expect(err).ToNot(HaveOccurred())
+	defer s.Close()
+
+	opts, err := NewOptions("gredis://" + s.l.Addr().String() + ",localhost:16379")
+	Expect(err).ToNot(HaveOccurred())
+
+	failedOver := make(chan string, 1)
+	opts.FailoverInterval = 10 * time.Millisecond
+	opts.OnFailover = func(addr string) {
+		failedOver <- addr
+	}
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	Expect(client.Addr()).To(Equal(s.l.Addr().String()))
+
+	s.Close()
+
+	select {
+	case addr := <-failedOver:
+		Expect(addr).To(Equal("localhost:16379"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("failover did not happen")
+	}
+
+	Expect(client.Addr()).To(Equal("localhost:16379"))
+	Expect(client.Stats().Reconnects).To(Equal(uint64(1)))
+
+	pong, err := client.Ping()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(pong).To(Equal("PONG"))
+}
+
+func TestFailoverPingsAreNotCounted(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newPongServer()
+	Expect(err).ToNot(HaveOccurred())
+	defer s.Close()
+
+	opts, err := NewOptions("gredis://" + s.l.Addr().String())
+	Expect(err).ToNot(HaveOccurred())
+	opts.FailoverInterval = 5 * time.Millisecond
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	Expect(client.Stats().Commands).ToNot(HaveKey("PING"))
+
+	_, err = client.Ping()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client.Stats().Commands["PING"].Calls).To(Equal(uint64(1)))
+}
@@ -1,6 +1,9 @@
 package gredis
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net"
 	"os"
 	"sync"
@@ -10,14 +13,64 @@ import (
 )
 
 var defaultProtocol *resp.Protocol
-var defaultTraceProtocol *resp.Protocol
+
+// ErrClosed is returned by every call made after Close or Shutdown
+var ErrClosed = errors.New("client is closed")
+
+// ErrBroken is returned by Receive when the connection timed out after commands were sent, their replies
+// are lost. The next Send replaces the connection.
+var ErrBroken = errors.New("connection is broken")
+
+// ErrTimeout matches errors of commands which timed out, use errors.Is(err, ErrTimeout)
+var ErrTimeout = errors.New("timeout")
+
+// TimeoutError is returned when command is not written or its reply is not read in time. The connection is
+// broken after timeout and is replaced by the next command.
+type TimeoutError struct {
+	// Op is "write" or "read"
+	Op  string
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return e.Op + " timeout: " + e.Err.Error()
+}
+
+// Timeout reports that error is a timeout, so TimeoutError is net.Error
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary is false, because connection is broken after timeout
+func (e *TimeoutError) Temporary() bool {
+	return false
+}
+
+// Is matches ErrTimeout
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// Unwrap returns the underlying network error
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// defaultCommandTimeouts holds read timeouts of commands which differ from Options.ReadTimeout. Blocking
+// commands wait for data as long as their own timeout argument says, so they get no read deadline.
+var defaultCommandTimeouts = map[string]time.Duration{
+	"BLPOP":      NoTimeout,
+	"BRPOP":      NoTimeout,
+	"BRPOPLPUSH": NoTimeout,
+}
 
 func init() {
 	defaultProtocol = resp.NewProtocol()
-	defaultTraceProtocol = resp.NewProtocolWithLogging(os.Stdout)
 }
 
-// Client is connection to GRedis server
+// Client is connection to GRedis server. Commands sent by high level API, Do, Process and Pipeline are
+// serialized, so Client can be shared between goroutines. Low level Send, Flush and Receive are not: replies
+// are matched with commands only by order, so they must not be interleaved with other calls.
 type Client struct {
 	opts *Options
 
@@ -25,86 +78,285 @@ type Client struct {
 	conn net.Conn
 	r    *resp.Reader
 	w    *resp.Writer
+	addr string
+	// broken is set when connection timed out and has to be replaced before the next command
+	broken bool
+	// trace receives replies read past r, see KeysEach, it is nil when protocol trace is disabled
+	trace io.Writer
+
+	// connMu guards closed and replacing conn, so Close interrupts command in flight without waiting for mu
+	connMu sync.Mutex
+	closed bool
+
+	addrs   []string
+	addrIdx int
+	done    chan struct{}
+
+	stats *statsCollector
+
+	// hooksMu guards the hook chain, so hooks can be added while commands are processed
+	hooksMu             sync.RWMutex
+	hooks               []Hook
+	processHook         ProcessFunc
+	processPipelineHook ProcessPipelineFunc
 }
 
-// Dial establish connection to GRedis server with specified options
+// Dial establish connection to GRedis server with specified options. When options hold several addresses,
+// they are tried in turn until connection succeeds.
 func Dial(opts *Options) (*Client, error) {
+	client := &Client{
+		opts:  opts,
+		addrs: opts.addresses(),
+		stats: newStatsCollector(),
+	}
+
+	var err error
+	for i, addr := range client.addrs {
+		if err = client.connect(addr); err == nil {
+			client.addrIdx = i
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(client.addrs) > 1 && opts.FailoverInterval > 0 {
+		client.done = make(chan struct{})
+		go client.monitor(opts.FailoverInterval)
+	}
+
+	return client, nil
+}
+
+// connect establishes connection to addr and replaces current connection with it
+func (client *Client) connect(addr string) error {
+	tmp, err := client.dial(addr)
+	if err != nil {
+		return err
+	}
+
+	client.mu.Lock()
+	if client.isClosed() {
+		client.mu.Unlock()
+		tmp.conn.Close()
+		return ErrClosed
+	}
+	old := client.conn
+	client.use(tmp, addr)
+	client.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	return nil
+}
+
+// ensureConn replaces broken connection with a new one to the same address, it is called with mu held
+func (client *Client) ensureConn() error {
+	if client.isClosed() {
+		return ErrClosed
+	}
+	if !client.broken {
+		return nil
+	}
+
+	tmp, err := client.dial(client.addr)
+	if err != nil {
+		return err
+	}
+
+	client.use(tmp, client.addr)
+	client.stats.reconnected()
+
+	return nil
+}
+
+// isClosed reports whether Close was called
+func (client *Client) isClosed() bool {
+	client.connMu.Lock()
+	defer client.connMu.Unlock()
+
+	return client.closed
+}
+
+// use switches client to connection established by dial, it is called with mu held
+func (client *Client) use(tmp *Client, addr string) {
+	client.connMu.Lock()
+	client.conn = tmp.conn
+	client.connMu.Unlock()
+
+	client.r, client.w, client.trace = tmp.r, tmp.w, tmp.trace
+	client.addr = addr
+	client.broken = false
+}
+
+// dial establishes connection to addr and authenticates it. The connection is returned in a separate
+// client, so handshake does not interfere with commands on current connection.
+func (client *Client) dial(addr string) (*Client, error) {
+	opts := client.opts
+
 	dialer := net.Dialer{
 		Timeout: opts.Timeout,
 	}
 
-	conn, err := dialer.Dial("tcp", net.JoinHostPort(opts.Host, opts.Port))
+	conn, err := dialer.Dial("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
 
-	var protocol *resp.Protocol
-	if opts.TraceProtocol {
-		protocol = defaultTraceProtocol
-	} else {
-		protocol = defaultProtocol
+	conn = &statsConn{Conn: conn, stats: client.stats}
+
+	protocol := defaultProtocol
+	var trace io.Writer
+	if opts.TraceProtocol || opts.TraceWriter != nil {
+		w := opts.TraceWriter
+		if w == nil {
+			w = os.Stdout
+		}
+		tw := newTraceWriter(w, opts.ClientName, conn.RemoteAddr().String(), opts.TraceMaxLen)
+		protocol = resp.NewProtocolWithLogging(tw)
+		trace = tw
 	}
 
-	client := &Client{
-		opts: opts,
-		conn: conn,
-		r:    resp.NewReader(conn, protocol),
-		w:    resp.NewWriter(conn, protocol),
+	tmp := &Client{
+		opts:  opts,
+		conn:  conn,
+		r:     resp.NewReader(conn, protocol),
+		w:     resp.NewWriter(conn, protocol),
+		trace: trace,
+		stats: client.stats,
 	}
 
 	if opts.Password != "" {
-		if _, err := client.Auth(opts.Password); err != nil {
+		if _, err := tmp.Auth(opts.Password); err != nil {
 			conn.Close()
 			return nil, err
 		}
 	}
 
 	if opts.DB != 0 {
-		if _, err := client.Select(opts.DB); err != nil {
+		if _, err := tmp.Select(opts.DB); err != nil {
 			conn.Close()
 			return nil, err
 		}
 	}
 
-	return client, nil
+	return tmp, nil
 }
 
-// Close flushes all pending writes and disconnect from GRedis server
-func (client *Client) Close() {
-	client.Flush()
-	client.conn.Close()
+// Close flushes all pending writes and disconnect from GRedis server. It returns the first error of flush
+// and closing the connection. Command waiting for reply, e.g. blocked `BLPOP`, is interrupted and returns
+// ErrClosed. Close can be called several times and from several goroutines, only the first call closes the
+// connection, the rest return nil.
+func (client *Client) Close() error {
+	client.connMu.Lock()
+	if client.closed {
+		client.connMu.Unlock()
+		return nil
+	}
+	client.closed = true
+	// command waiting for reply, e.g. BLPOP without timeout, returns ErrClosed and releases mu
+	client.conn.SetReadDeadline(time.Now())
+	client.connMu.Unlock()
+
+	if client.done != nil {
+		close(client.done)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.broken {
+		// connection was closed when it timed out
+		return nil
+	}
+
+	client.conn.SetWriteDeadline(deadline(client.opts.WriteTimeout))
+	err := client.flush()
+	if cerr := client.conn.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
 }
 
-// Send sends command to GRedis server
+// Send sends command to GRedis server. Connection broken by timeout is replaced first.
 func (client *Client) Send(cmd []byte, args ...[]byte) error {
-	err := client.w.WriteCmd(cmd, args...)
-	if err != nil {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if err := client.ensureConn(); err != nil {
 		return err
 	}
 
-	return client.Flush()
+	return client.send(cmd, args...)
+}
+
+func (client *Client) send(cmd []byte, args ...[]byte) error {
+	// large commands are written to connection before flush
+	client.conn.SetWriteDeadline(deadline(client.opts.WriteTimeout))
+
+	if err := client.w.WriteCmd(cmd, args...); err != nil {
+		return client.checkTimeout("write", err)
+	}
+
+	return client.flush()
 }
 
-// Flush flushes all pending writes to GRedis server
+// Flush flushes all pending writes to GRedis server. Connection broken by timeout is replaced first.
 func (client *Client) Flush() error {
-	if client.opts.WriteTimeout != 0 {
-		client.conn.SetWriteDeadline(time.Now().Add(client.opts.ReadTimeout))
-		defer client.conn.SetWriteDeadline(time.Time{})
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if err := client.ensureConn(); err != nil {
+		return err
 	}
 
-	return client.w.Flush()
+	client.conn.SetWriteDeadline(deadline(client.opts.WriteTimeout))
+
+	return client.flush()
 }
 
-// Receive receives reply from GRedis server
+func (client *Client) flush() error {
+	if err := client.w.Flush(); err != nil {
+		return client.checkTimeout("write", err)
+	}
+
+	return nil
+}
+
+// Receive receives reply from GRedis server. ErrBroken is returned when the connection timed out since the
+// command was sent.
 func (client *Client) Receive() (*resp.Message, error) {
-	if client.opts.ReadTimeout != 0 {
-		client.conn.SetReadDeadline(time.Now().Add(client.opts.ReadTimeout))
-		defer client.conn.SetReadDeadline(time.Time{})
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.isClosed() {
+		return nil, ErrClosed
+	}
+	if client.broken {
+		return nil, ErrBroken
+	}
+
+	return client.receive(client.opts.ReadTimeout)
+}
+
+// receive receives reply waiting for it no longer than timeout, zero or NoTimeout means no limit
+func (client *Client) receive(timeout time.Duration) (*resp.Message, error) {
+	client.conn.SetReadDeadline(deadline(timeout))
+	// Close could set its deadline before ours
+	if client.isClosed() {
+		return nil, ErrClosed
 	}
 
 	msg, err := client.r.Read()
 	if err != nil {
-		return nil, err
+		if client.isClosed() {
+			return nil, ErrClosed
+		}
+		return nil, client.checkTimeout("read", err)
 	}
 
 	if msg.IsError() {
@@ -114,26 +366,138 @@ func (client *Client) Receive() (*resp.Message, error) {
 	return msg, nil
 }
 
-// Do sends command to GRedis server and receives reply from GRedis server
+// Do sends command to GRedis server and receives reply from GRedis server. The command goes through
+// the hook chain.
 func (client *Client) Do(cmd []byte, args ...[]byte) (*resp.Message, error) {
-	err := client.Send(cmd, args...)
+	c := cmdPool.Get().(*Cmd)
+	c.Name, c.Args = cmd, args
+
+	err := client.Process(c)
+	reply := c.Reply
+
+	*c = Cmd{}
+	cmdPool.Put(c)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// DoContext is like Do, but the command is interrupted when ctx is done and ctx.Err() is returned. Deadline
+// of ctx replaces read timeout of the command. Command is not sent if ctx is already done.
+//
+// The connection is closed to interrupt the command and replaced before the next one.
+func (client *Client) DoContext(ctx context.Context, cmd []byte, args ...[]byte) (*resp.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c := NewCmd(cmd, args...)
+	c.ctx = ctx
+	if _, ok := ctx.Deadline(); ok {
+		c.Timeout = NoTimeout
+	}
+
+	if err := client.Process(c); err != nil {
+		return nil, err
+	}
+
+	return c.Reply, nil
+}
+
+func noWatch() bool {
+	return false
+}
+
+// watch closes the connection when ctx is done before the returned stop function is called, so blocked
+// reads and writes return. stop reports whether the connection was closed. It is called with mu held.
+func (client *Client) watch(ctx context.Context) (stop func() bool) {
+	if ctx == nil || ctx.Done() == nil {
+		return noWatch
+	}
+
+	conn := client.conn
+	stopc := make(chan struct{})
+	closed := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			closed <- true
+		case <-stopc:
+			closed <- false
+		}
+	}()
+
+	return func() bool {
+		close(stopc)
+		return <-closed
+	}
+}
+
+func (client *Client) do(timeout time.Duration, cmd []byte, args ...[]byte) (*resp.Message, error) {
+	err := client.send(cmd, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return client.Receive()
+	return client.receive(timeout)
 }
 
-func toBulkArray(args []string, keys ...string) [][]byte {
-	res := make([][]byte, 0, len(args)+len(keys))
+// readTimeout returns time to wait for reply of cmd: Cmd.Timeout, then Options.CommandTimeouts, then
+// defaultCommandTimeouts and finally Options.ReadTimeout
+func (client *Client) readTimeout(cmd *Cmd) time.Duration {
+	if cmd.Timeout != 0 {
+		return cmd.Timeout
+	}
+
+	if timeout, ok := client.opts.CommandTimeouts[string(cmd.Name)]; ok {
+		return timeout
+	}
+
+	if timeout, ok := defaultCommandTimeouts[string(cmd.Name)]; ok {
+		return timeout
+	}
+
+	return client.opts.ReadTimeout
+}
+
+// checkTimeout turns timeout into TimeoutError and marks connection broken. The rest of a partially
+// written command or read reply would desynchronize the stream, so the connection is closed and replaced
+// by the next command.
+func (client *Client) checkTimeout(op string, err error) error {
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		return err
+	}
+
+	client.broken = true
+	client.conn.Close()
+
+	return &TimeoutError{Op: op, Err: err}
+}
 
-	for _, value := range keys {
-		res = append(res, []byte(value))
+// deadline returns deadline for operation started now, zero time means no deadline
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
 	}
 
-	for _, value := range args {
-		res = append(res, []byte(value))
+	return time.Now().Add(timeout)
+}
+
+func isNetError(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
 	}
 
-	return res
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// isConnDropped reports whether err means the server closed the connection, unlike isNetError it does not
+// match timeouts
+func isConnDropped(err error) bool {
+	return isNetError(err) && !errors.Is(err, ErrTimeout)
 }
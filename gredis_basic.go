@@ -1,9 +1,5 @@
 package gredis
 
-import (
-	"strconv"
-)
-
 // List of basic commands
 var (
 	AuthCommand     = []byte("AUTH")
@@ -16,6 +12,9 @@ var (
 	KeysCommand    = []byte("KEYS")
 	ExistsCommand  = []byte("EXISTS")
 	ExpireCommand  = []byte("EXPIRE")
+	PExpireCommand = []byte("PEXPIRE")
+	TTLCommand     = []byte("TTL")
+	TypeCommand    = []byte("TYPE")
 )
 
 // Auth requests for authentication in a password-protected GRedis server. GRedis can be instructed to
@@ -23,7 +22,10 @@ var (
 //
 // Returns true if success, otherwise false.
 func (client *Client) Auth(password string) (bool, error) {
-	_, err := client.Do(AuthCommand, []byte(password))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	_, err := client.Do(AuthCommand, a.addString(password).bulks()...)
 	if err != nil {
 		return false, err
 	}
@@ -35,7 +37,10 @@ func (client *Client) Auth(password string) (bool, error) {
 //
 // Returns true if success, otherwise false.
 func (client *Client) Select(db int) (bool, error) {
-	_, err := client.Do(SelectCommand, []byte(strconv.Itoa(db)))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	_, err := client.Do(SelectCommand, a.addInt(db).bulks()...)
 	if err != nil {
 		return false, err
 	}
@@ -45,7 +50,10 @@ func (client *Client) Select(db int) (bool, error) {
 
 // Echo returns a copy of the argument as a bulk if success, otherwise nil.
 func (client *Client) Echo(message string) ([]byte, error) {
-	msg, err := client.Do(EchoCommand, []byte(message))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(EchoCommand, a.addString(message).bulks()...)
 	if err != nil {
 		return nil, err
 	}
@@ -65,7 +73,10 @@ func (client *Client) Ping() (string, error) {
 
 // PingMsg returns a copy of the argument as a bulk if success, otherwise nil.
 func (client *Client) PingMsg(message string) ([]byte, error) {
-	msg, err := client.Do(EchoCommand, []byte(message))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(EchoCommand, a.addString(message).bulks()...)
 	if err != nil {
 		return nil, err
 	}
@@ -73,15 +84,38 @@ func (client *Client) PingMsg(message string) ([]byte, error) {
 	return msg.BulkString(), nil
 }
 
+// Shutdown modifiers
+var (
+	saveModifier   = []byte("SAVE")
+	noSaveModifier = []byte("NOSAVE")
+)
+
 // Shutdown behavior is the following:
 //  Send command to the server
-//  Close connection to the server.
+//  Wait until the server drops connection, which is the expected reply to successful shutdown
+//  Close the client, every subsequent call returns ErrClosed.
+// Error reply of the server is returned and the client stays open.
 func (client *Client) Shutdown() error {
-	err := client.Send(ShutdownCommand)
-	if err != nil {
+	return client.shutdown()
+}
+
+// ShutdownSave is the same as Shutdown, but asks the server to save data before shutting down
+func (client *Client) ShutdownSave() error {
+	return client.shutdown(saveModifier)
+}
+
+// ShutdownNoSave is the same as Shutdown, but asks the server to not save data before shutting down
+func (client *Client) ShutdownNoSave() error {
+	return client.shutdown(noSaveModifier)
+}
+
+func (client *Client) shutdown(args ...[]byte) error {
+	_, err := client.Do(ShutdownCommand, args...)
+	if err != nil && !isConnDropped(err) {
 		return err
 	}
 
+	// the connection is gone, errors of closing it are expected
 	client.Close()
 
 	return nil
@@ -105,7 +139,10 @@ func (client *Client) Command() ([][]byte, error) {
 
 // Keys returns Bulk Array of all keys matching **regexp** pattern.
 func (client *Client) Keys(pattern string) ([][]byte, error) {
-	msg, err := client.Do(KeysCommand, []byte(pattern))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(KeysCommand, a.addString(pattern).bulks()...)
 	if err != nil {
 		return nil, err
 	}
@@ -127,7 +164,10 @@ func (client *Client) Keys(pattern string) ([][]byte, error) {
 // The user should be aware that if the same existing key is mentioned in the arguments multiple times,
 // it will be counted multiple times. So if `somekey` exists, `Exists("somekey", "somekey")` will return 2.
 func (client *Client) Exists(key string, keys ...string) (int, error) {
-	msg, err := client.Do(ExistsCommand, toBulkArray(keys, key)...)
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(ExistsCommand, a.addString(key).addStrings(keys).bulks()...)
 	if err != nil {
 		return 0, err
 	}
@@ -139,10 +179,57 @@ func (client *Client) Exists(key string, keys ...string) (int, error) {
 //  1 if the timeout was set.
 //  0 if key does not exist or the timeout could not be set.
 func (client *Client) Expire(key string, seconds int) (int, error) {
-	msg, err := client.Do(ExpireCommand, []byte(key), []byte(strconv.Itoa(seconds)))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(ExpireCommand, a.addString(key).addInt(seconds).bulks()...)
 	if err != nil {
 		return 0, err
 	}
 
 	return msg.Int(), nil
 }
+
+// PExpire works exactly like Expire but the time to live of the key is specified in milliseconds.
+//  1 if the timeout was set.
+//  0 if key does not exist or the timeout could not be set.
+func (client *Client) PExpire(key string, milliseconds int) (int, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(PExpireCommand, a.addString(key).addInt(milliseconds).bulks()...)
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.Int(), nil
+}
+
+// TTL returns the remaining time to live of a key that has a timeout.
+//  -2 if the key does not exist.
+//  -1 if the key exists but has no associated expire.
+func (client *Client) TTL(key string) (int, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(TTLCommand, a.addString(key).bulks()...)
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.Int(), nil
+}
+
+// Type returns the string representation of the type of the value stored at key. The different types that
+// can be returned are: `string`, `list` and `hash`. `none` is returned if key does not exist.
+func (client *Client) Type(key string) (string, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(TypeCommand, a.addString(key).bulks()...)
+	if err != nil {
+		return "", err
+	}
+
+	return msg.String(), nil
+}
@@ -2,9 +2,10 @@ package gredis
 
 // List of key value commands
 var (
-	SetCommand = []byte("SET")
-	GetCommand = []byte("GET")
-	DelCommand = []byte("DEL")
+	SetCommand  = []byte("SET")
+	GetCommand  = []byte("GET")
+	DelCommand  = []byte("DEL")
+	IncrCommand = []byte("INCR")
 )
 
 // Set key to hold the string value. If key already holds a value, it is overwritten, regardless of its type.
@@ -12,7 +13,23 @@ var (
 //
 // Returns true if success, otherwise false.
 func (client *Client) Set(key string, value string) (bool, error) {
-	_, err := client.Do(SetCommand, []byte(key), []byte(value))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	_, err := client.Do(SetCommand, a.addString(key).addString(value).bulks()...)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SetBytes is binary-safe variant of Set, value is sent without conversion.
+func (client *Client) SetBytes(key string, value []byte) (bool, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	_, err := client.Do(SetCommand, a.addString(key).addBytes(value).bulks()...)
 	if err != nil {
 		return false, err
 	}
@@ -23,7 +40,10 @@ func (client *Client) Set(key string, value string) (bool, error) {
 // Get the value of key. If the key does not exist the special value nil is returned. An error is returned
 // if the value stored at key is not a string, because `GET` only handles string values.
 func (client *Client) Get(key string) ([]byte, error) {
-	msg, err := client.Do(GetCommand, []byte(key))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(GetCommand, a.addString(key).bulks()...)
 	if err != nil {
 		return nil, err
 	}
@@ -31,11 +51,31 @@ func (client *Client) Get(key string) ([]byte, error) {
 	return msg.BulkString(), nil
 }
 
+// Incr increments the number stored at key by one. If the key does not exist, it is set to 0 before
+// performing the operation. An error is returned if the key contains a value of the wrong type or contains
+// a string that can not be represented as integer.
+//
+// Returns the value of key after the increment.
+func (client *Client) Incr(key string) (int, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(IncrCommand, a.addString(key).bulks()...)
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.Int(), nil
+}
+
 // Del removes the specified keys. A key is ignored if it does not exist.
 //
 // Returns the number of keys that were removed.
 func (client *Client) Del(key string, keys ...string) (int, error) {
-	msg, err := client.Do(DelCommand, toBulkArray(keys, key)...)
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(DelCommand, a.addString(key).addStrings(keys).bulks()...)
 	if err != nil {
 		return 0, err
 	}
@@ -7,6 +7,7 @@ var (
 	HDelCommand    = []byte("HDEL")
 	HLenCommand    = []byte("HLEN")
 	HExistsCommand = []byte("HEXISTS")
+	HKeysCommand   = []byte("HKEYS")
 )
 
 // HSet sets field in the hash stored at key to value. If key does not exist, a new key holding a hash is
@@ -14,7 +15,23 @@ var (
 //  1 if field is a new field in the hash and value was set.
 //  0 if field already exists in the hash and the value was updated.
 func (client *Client) HSet(key string, field string, value string) (int, error) {
-	msg, err := client.Do(HSetCommand, []byte(key), []byte(field), []byte(value))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(HSetCommand, a.addString(key).addString(field).addString(value).bulks()...)
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.Int(), nil
+}
+
+// HSetBytes is binary-safe variant of HSet, value is sent without conversion.
+func (client *Client) HSetBytes(key string, field string, value []byte) (int, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(HSetCommand, a.addString(key).addString(field).addBytes(value).bulks()...)
 	if err != nil {
 		return 0, err
 	}
@@ -24,7 +41,10 @@ func (client *Client) HSet(key string, field string, value string) (int, error)
 
 // HGet returns the value associated with field in the hash stored at key.
 func (client *Client) HGet(key string, field string) ([]byte, error) {
-	msg, err := client.Do(HGetCommand, []byte(key), []byte(field))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(HGetCommand, a.addString(key).addString(field).bulks()...)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +60,10 @@ func (client *Client) HGet(key string, field string) ([]byte, error) {
 // within this hash are ignored. If key does not exist, it is treated as an empty hash and this
 // command returns 0.
 func (client *Client) HDel(key string, field string, fields ...string) (int, error) {
-	msg, err := client.Do(HDelCommand, toBulkArray(fields, key, field)...)
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(HDelCommand, a.addString(key).addString(field).addStrings(fields).bulks()...)
 	if err != nil {
 		return 0, err
 	}
@@ -50,7 +73,10 @@ func (client *Client) HDel(key string, field string, fields ...string) (int, err
 
 // HLen returns the number of fields contained in the hash stored at key or 0 when key does not exist.
 func (client *Client) HLen(key string) (int, error) {
-	msg, err := client.Do(HLenCommand, []byte(key))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(HLenCommand, a.addString(key).bulks()...)
 	if err != nil {
 		return 0, err
 	}
@@ -62,10 +88,32 @@ func (client *Client) HLen(key string) (int, error) {
 //  1 if the hash contains field.
 //  0 if the hash does not contain field, or key does not exist.
 func (client *Client) HExists(key string, field string) (int, error) {
-	msg, err := client.Do(HExistsCommand, []byte(key), []byte(field))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(HExistsCommand, a.addString(key).addString(field).bulks()...)
 	if err != nil {
 		return 0, err
 	}
 
 	return msg.Int(), nil
 }
+
+// HKeys returns all field names in the hash stored at key or empty list when key does not exist.
+func (client *Client) HKeys(key string) ([][]byte, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(HKeysCommand, a.addString(key).bulks()...)
+	if err != nil {
+		return nil, err
+	}
+
+	arr := msg.Array()
+	res := make([][]byte, 0, len(arr))
+	for _, field := range arr {
+		res = append(res, field.BulkString())
+	}
+
+	return res, nil
+}
@@ -0,0 +1,357 @@
+package gredis
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	errInvalidStruct    = errors.New("value must be a struct or a pointer to struct")
+	errInvalidStructPtr = errors.New("value must be a non-nil pointer to struct")
+	errUnsupportedType  = errors.New("unsupported type")
+)
+
+type structField struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+type fieldValue struct {
+	name  string
+	value string
+}
+
+// HSetStruct sets fields of the hash stored at key to values of exported fields of v. Field names are taken
+// from `gredis:"name,omitempty"` tags or field names when tag is absent, fields tagged with `gredis:"-"` are
+// skipped. Empty values of fields with omitempty option are not touched, fields holding nil pointers without
+// omitempty are deleted from the hash. All fields are sent in a single pipeline.
+//
+// Supported field types are string, all integer and float kinds, bool, time.Time, []byte, pointers to them
+// and types implementing encoding.TextMarshaler.
+//
+// Returns the number of new fields in the hash.
+func (client *Client) HSetStruct(key string, v interface{}) (int, error) {
+	fields, nilFields, err := structToFields(v)
+	if err != nil {
+		return 0, err
+	}
+
+	cmds := make([]*Cmd, 0, len(fields)+1)
+	for _, f := range fields {
+		cmds = append(cmds, NewCmd(HSetCommand, []byte(key), []byte(f.name), []byte(f.value)))
+	}
+	if len(nilFields) > 0 {
+		args := make([][]byte, 0, len(nilFields)+1)
+		args = append(args, []byte(key))
+		for _, name := range nilFields {
+			args = append(args, []byte(name))
+		}
+		cmds = append(cmds, NewCmd(HDelCommand, args...))
+	}
+	if len(cmds) == 0 {
+		return 0, nil
+	}
+
+	err = client.Pipeline(cmds...)
+
+	cnt := 0
+	for _, cmd := range cmds[:len(fields)] {
+		if cmd.Err == nil {
+			cnt += cmd.Reply.Int()
+		}
+	}
+
+	return cnt, err
+}
+
+// HGetStruct sets exported fields of struct pointed by v to values of the hash stored at key. Fields absent
+// in the hash are left unchanged. All fields are read in a single pipeline. See HSetStruct for mapping rules
+// and supported types.
+func (client *Client) HGetStruct(key string, v interface{}) error {
+	return fieldsToStruct(v, func(names []string) ([][]byte, error) {
+		cmds := make([]*Cmd, len(names))
+		for i, name := range names {
+			cmds[i] = NewCmd(HGetCommand, []byte(key), []byte(name))
+		}
+		if err := client.Pipeline(cmds...); err != nil {
+			return nil, err
+		}
+
+		res := make([][]byte, len(cmds))
+		for i, cmd := range cmds {
+			if !cmd.Reply.IsNil() {
+				res[i] = cmd.Reply.BulkString()
+			}
+		}
+
+		return res, nil
+	})
+}
+
+func structFields(t reflect.Type) []structField {
+	res := make([]structField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		f := structField{index: i, name: sf.Name}
+
+		tag := sf.Tag.Get("gredis")
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		if parts[0] != "" {
+			f.name = parts[0]
+		}
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				f.omitEmpty = true
+			}
+		}
+
+		res = append(res, f)
+	}
+
+	return res
+}
+
+// structToFields returns values of fields of v and names of fields holding nil pointers
+func structToFields(v interface{}) ([]fieldValue, []string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, nil, errInvalidStruct
+	}
+
+	var res []fieldValue
+	var nilFields []string
+	for _, f := range structFields(rv.Type()) {
+		fv := rv.Field(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+
+		value, ok, err := encodeField(fv)
+		if err == errUnsupportedType {
+			return nil, nil, fmt.Errorf("unsupported type %s of field %s", fv.Type(), f.name)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid value of field %s: %v", f.name, err)
+		}
+		if ok {
+			res = append(res, fieldValue{name: f.name, value: value})
+		} else {
+			nilFields = append(nilFields, f.name)
+		}
+	}
+
+	return res, nilFields, nil
+}
+
+// fieldsToStruct decodes values returned by get into fields of struct pointed by v, get returns nil for
+// absent fields
+func fieldsToStruct(v interface{}, get func(names []string) ([][]byte, error)) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errInvalidStructPtr
+	}
+	rv = rv.Elem()
+
+	fields := structFields(rv.Type())
+	for _, f := range fields {
+		if !isSupportedType(rv.Field(f.index).Type()) {
+			return fmt.Errorf("unsupported type %s of field %s", rv.Field(f.index).Type(), f.name)
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.name
+	}
+
+	values, err := get(names)
+	if err != nil {
+		return err
+	}
+
+	for i, f := range fields {
+		data := values[i]
+		if data == nil {
+			continue
+		}
+
+		if err := decodeField(rv.Field(f.index), data); err != nil {
+			return fmt.Errorf("invalid value of field %s: %v", f.name, err)
+		}
+	}
+
+	return nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+
+	return false
+}
+
+func isSupportedType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+
+	return false
+}
+
+// encodeField returns string representation of v, false is returned for nil pointers
+func encodeField(v reflect.Value) (string, bool, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if !isSupportedType(v.Type()) && !v.Type().Implements(textMarshalerType) {
+				return "", false, errUnsupportedType
+			}
+			return "", false, nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), true, nil
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), true, err
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textMarshalerType) {
+		text, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), true, err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32), true, nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), true, nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), true, nil
+		}
+	}
+
+	return "", false, errUnsupportedType
+}
+
+func decodeField(v reflect.Value, data []byte) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		t, err := time.Parse(time.RFC3339Nano, string(data))
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(data)
+	}
+
+	s := string(data)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		v.SetBytes(append([]byte(nil), data...))
+	}
+
+	return nil
+}
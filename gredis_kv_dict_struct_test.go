@@ -0,0 +1,183 @@
+package gredis
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+type structRecord struct {
+	Name     string    `gredis:"name"`
+	Age      int8      `gredis:"age"`
+	Balance  float64   `gredis:"balance"`
+	Visits   uint32    `gredis:"visits,omitempty"`
+	Active   bool      `gredis:"active"`
+	Created  time.Time `gredis:"created"`
+	Avatar   []byte    `gredis:"avatar,omitempty"`
+	Nickname *string   `gredis:"nickname"`
+	IP       net.IP    `gredis:"ip"`
+	Ignored  string    `gredis:"-"`
+	Untagged string
+	private  string
+}
+
+func TestStructFieldsMapping(t *testing.T) {
+	RegisterTestingT(t)
+
+	nickname := "nick"
+	rec := structRecord{
+		Name:     "name",
+		Age:      -42,
+		Balance:  12.5,
+		Active:   true,
+		Created:  time.Date(2017, 5, 1, 10, 20, 30, 400, time.UTC),
+		Nickname: &nickname,
+		IP:       net.ParseIP("127.0.0.1"),
+		Ignored:  "ignored",
+		Untagged: "untagged",
+		private:  "private",
+	}
+
+	fields, nilFields, err := structToFields(&rec)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(nilFields).To(BeEmpty())
+
+	hash := make(map[string][]byte)
+	for _, f := range fields {
+		hash[f.name] = []byte(f.value)
+	}
+
+	Expect(hash).To(Equal(map[string][]byte{
+		"name":     []byte("name"),
+		"age":      []byte("-42"),
+		"balance":  []byte("12.5"),
+		"active":   []byte("true"),
+		"created":  []byte("2017-05-01T10:20:30.0000004Z"),
+		"nickname": []byte("nick"),
+		"ip":       []byte("127.0.0.1"),
+		"Untagged": []byte("untagged"),
+	}))
+
+	var res structRecord
+	err = fieldsToStruct(&res, func(names []string) ([][]byte, error) {
+		values := make([][]byte, len(names))
+		for i, name := range names {
+			values[i] = hash[name]
+		}
+		return values, nil
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	rec.Ignored = ""
+	rec.private = ""
+	Expect(res).To(Equal(rec))
+}
+
+func TestStructFieldsErrors(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, _, err := structToFields("string")
+	Expect(err).To(MatchError("value must be a struct or a pointer to struct"))
+
+	_, _, err = structToFields(struct{ Values []int }{[]int{1}})
+	Expect(err).To(MatchError("unsupported type []int of field Values"))
+
+	err = fieldsToStruct(structRecord{}, nil)
+	Expect(err).To(MatchError("value must be a non-nil pointer to struct"))
+
+	err = fieldsToStruct(&struct{ Values map[string]int }{}, nil)
+	Expect(err).To(MatchError("unsupported type map[string]int of field Values"))
+
+	var rec struct {
+		Age int8 `gredis:"age"`
+	}
+	err = fieldsToStruct(&rec, func(names []string) ([][]byte, error) {
+		return [][]byte{[]byte("1000")}, nil
+	})
+	Expect(err).To(HaveOccurred())
+	Expect(err.Error()).To(HavePrefix("invalid value of field age: "))
+}
+
+func TestHSetStructAndHGetStruct(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	rec := structRecord{Name: "name", Age: 30, Visits: 3, Avatar: []byte{0, 1, 2}}
+	cnt, err := client.HSetStruct("user:1", &rec)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(cnt).To(Equal(9))
+
+	var res structRecord
+	Expect(client.HGetStruct("user:1", &res)).To(Succeed())
+	Expect(res.Name).To(Equal("name"))
+	Expect(res.Age).To(Equal(int8(30)))
+	Expect(res.Visits).To(Equal(uint32(3)))
+	Expect(res.Avatar).To(Equal([]byte{0, 1, 2}))
+	Expect(res.Nickname).To(BeNil())
+
+	nickname := "nick"
+	rec.Nickname = &nickname
+	_, err = client.HSetStruct("user:1", &rec)
+	Expect(err).ToNot(HaveOccurred())
+
+	// nil pointer without omitempty deletes the field
+	rec.Nickname = nil
+	_, err = client.HSetStruct("user:1", &rec)
+	Expect(err).ToNot(HaveOccurred())
+
+	exists, err := client.HExists("user:1", "nickname")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(exists).To(Equal(0))
+}
+
+func TestHSetStructPipeline(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	hook := &recordHook{}
+	client.AddHook(hook)
+
+	var rec struct {
+		Name     string  `gredis:"name"`
+		City     string  `gredis:"city,omitempty"`
+		Nickname *string `gredis:"nickname"`
+		Email    *string `gredis:"email"`
+		Phone    *string `gredis:"phone,omitempty"`
+	}
+	rec.Name = "name"
+
+	_, err = client.HSetStruct("user:1", &rec)
+	Expect(err).ToNot(HaveOccurred())
+
+	Expect(client.HGetStruct("user:1", &rec)).To(Succeed())
+
+	Expect(hook.cmds).To(BeEmpty())
+	Expect(hook.pipelines).To(Equal([][]string{
+		{"HSET", "HDEL"},
+		{"HGET", "HGET", "HGET", "HGET", "HGET"},
+	}))
+}
@@ -1,7 +1,5 @@
 package gredis
 
-import "strconv"
-
 // List of key value list commands
 var (
 	LPushCommand   = []byte("LPUSH")
@@ -12,6 +10,10 @@ var (
 	LInsertCommand = []byte("LINSERT")
 	LIndexCommand  = []byte("LINDEX")
 	LRangeCommand  = []byte("LRANGE")
+	LRemCommand    = []byte("LREM")
+
+	RPopLPushCommand  = []byte("RPOPLPUSH")
+	BRPopLPushCommand = []byte("BRPOPLPUSH")
 )
 
 var (
@@ -30,7 +32,10 @@ var (
 //
 // Returns the length of the list after the push operations.
 func (client *Client) LPush(key string, value string, values ...string) (int, error) {
-	msg, err := client.Do(LPushCommand, toBulkArray(values, key, value)...)
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(LPushCommand, a.addString(key).addString(value).addStrings(values).bulks()...)
 	if err != nil {
 		return 0, err
 	}
@@ -49,7 +54,36 @@ func (client *Client) LPush(key string, value string, values ...string) (int, er
 //
 //Returns the length of the list after the push operations.
 func (client *Client) RPush(key string, value string, values ...string) (int, error) {
-	msg, err := client.Do(RPushCommand, toBulkArray(values, key, value)...)
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(RPushCommand, a.addString(key).addString(value).addStrings(values).bulks()...)
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.Int(), nil
+}
+
+// LPushBytes is binary-safe variant of LPush, values are sent without conversion.
+func (client *Client) LPushBytes(key string, value []byte, values ...[]byte) (int, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(LPushCommand, a.addString(key).addBytes(value).addBytesList(values).bulks()...)
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.Int(), nil
+}
+
+// RPushBytes is binary-safe variant of RPush, values are sent without conversion.
+func (client *Client) RPushBytes(key string, value []byte, values ...[]byte) (int, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(RPushCommand, a.addString(key).addBytes(value).addBytesList(values).bulks()...)
 	if err != nil {
 		return 0, err
 	}
@@ -59,7 +93,10 @@ func (client *Client) RPush(key string, value string, values ...string) (int, er
 
 // LPop removes and returns the first element of the list stored at key.
 func (client *Client) LPop(key string) ([]byte, error) {
-	msg, err := client.Do(LPopCommand, []byte(key))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(LPopCommand, a.addString(key).bulks()...)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +106,10 @@ func (client *Client) LPop(key string) ([]byte, error) {
 
 // RPop removes and returns the last element of the list stored at key.
 func (client *Client) RPop(key string) ([]byte, error) {
-	msg, err := client.Do(RPopCommand, []byte(key))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(RPopCommand, a.addString(key).bulks()...)
 	if err != nil {
 		return nil, err
 	}
@@ -77,10 +117,52 @@ func (client *Client) RPop(key string) ([]byte, error) {
 	return msg.BulkString(), nil
 }
 
+// RPopLPush atomically removes the last element of the list stored at source, pushes it to the head of the
+// list stored at destination and returns it. nil is returned if source does not exist.
+func (client *Client) RPopLPush(source string, destination string) ([]byte, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(RPopLPushCommand, a.addString(source).addString(destination).bulks()...)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.IsNil() {
+		return nil, nil
+	}
+
+	return msg.BulkString(), nil
+}
+
+// BRPopLPush is the blocking variant of RPopLPush. When source is empty it blocks the connection until
+// another client pushes to it or timeout in seconds passes, 0 blocks indefinitely. nil is returned when
+// timeout has passed.
+//
+// Blocking commands have no read timeout unless it is set by Options.CommandTimeouts.
+func (client *Client) BRPopLPush(source string, destination string, timeout int) ([]byte, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(BRPopLPushCommand, a.addString(source).addString(destination).addInt(timeout).bulks()...)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.IsNil() {
+		return nil, nil
+	}
+
+	return msg.BulkString(), nil
+}
+
 // LLen returns the length of the list stored at key. If key does not exist, it is interpreted as an empty list
 // and `0` is returned. An error is returned when the value stored at key is not a list.
 func (client *Client) LLen(key string) (int, error) {
-	msg, err := client.Do(LLenCommand, []byte(key))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(LLenCommand, a.addString(key).bulks()...)
 	if err != nil {
 		return 0, err
 	}
@@ -99,7 +181,28 @@ func (client *Client) LInsert(key string, before bool, pivot string, value strin
 		place = insertAfter
 	}
 
-	msg, err := client.Do(LInsertCommand, []byte(key), place, []byte(pivot), []byte(value))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(LInsertCommand, a.addString(key).addBytes(place).addString(pivot).addString(value).bulks()...)
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.Int(), nil
+}
+
+// LInsertBytes is binary-safe variant of LInsert, pivot and value are sent without conversion.
+func (client *Client) LInsertBytes(key string, before bool, pivot []byte, value []byte) (int, error) {
+	place := insertBefore
+	if !before {
+		place = insertAfter
+	}
+
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(LInsertCommand, a.addString(key).addBytes(place).addBytes(pivot).addBytes(value).bulks()...)
 	if err != nil {
 		return 0, err
 	}
@@ -114,7 +217,10 @@ func (client *Client) LInsert(key string, before bool, pivot string, value strin
 //
 // When the value at key is not a list, an error is returned.
 func (client *Client) LIndex(key string, index int) ([]byte, error) {
-	msg, err := client.Do(LIndexCommand, []byte(key), []byte(strconv.Itoa(index)))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(LIndexCommand, a.addString(key).addInt(index).bulks()...)
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +235,10 @@ func (client *Client) LIndex(key string, index int) ([]byte, error) {
 // These offsets can also be negative numbers indicating offsets starting at the end of the list.
 // For example, -1 is the last element of the list, -2 the penultimate, and so on.
 func (client *Client) LRange(key string, start int, stop int) ([][]byte, error) {
-	msg, err := client.Do(LRangeCommand, []byte(key), []byte(strconv.Itoa(start)), []byte(strconv.Itoa(stop)))
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(LRangeCommand, a.addString(key).addInt(start).addInt(stop).bulks()...)
 	if err != nil {
 		return nil, err
 	}
@@ -142,3 +251,20 @@ func (client *Client) LRange(key string, start int, stop int) ([][]byte, error)
 
 	return res, nil
 }
+
+// LRem removes the first count occurrences of elements equal to value from the list stored at key. count
+// greater than 0 removes elements moving from head to tail, less than 0 from tail to head and 0 removes all
+// elements equal to value.
+//
+// Returns the number of removed elements.
+func (client *Client) LRem(key string, count int, value string) (int, error) {
+	a := getCmdArgs()
+	defer putCmdArgs(a)
+
+	msg, err := client.Do(LRemCommand, a.addString(key).addInt(count).addString(value).bulks()...)
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.Int(), nil
+}
@@ -1,12 +1,18 @@
 package gredis
 
 import (
-	. "github.com/onsi/gomega"
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
 
 	"github.com/valery-barysok/gredisd/app"
 	"github.com/valery-barysok/gredisd/app/gredisd"
-	"time"
 )
 
 func TestValidDial(t *testing.T) {
@@ -297,3 +303,218 @@ func TestIntegrationForAllCommandsAtOnce(t *testing.T) {
 		client.Close()
 	}
 }
+
+// shutdownServer replies with +OK to every command, drops connection on `SHUTDOWN` and `SHUTDOWN NOSAVE` and
+// fails `SHUTDOWN SAVE` as a server which can not save data
+type shutdownServer struct {
+	l net.Listener
+
+	mu       sync.Mutex
+	received []string
+}
+
+func newShutdownServer() (*shutdownServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &shutdownServer{l: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go s.handle(conn)
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *shutdownServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		n, err := readBenchInt(r)
+		if err != nil {
+			return
+		}
+
+		args := make([]string, n)
+		for i := range args {
+			size, err := readBenchInt(r)
+			if err != nil {
+				return
+			}
+
+			arg := make([]byte, size+2)
+			if _, err := io.ReadFull(r, arg); err != nil {
+				return
+			}
+			args[i] = string(arg[:size])
+		}
+
+		cmd := strings.Join(args, " ")
+		s.mu.Lock()
+		s.received = append(s.received, cmd)
+		s.mu.Unlock()
+
+		reply := "+OK\r\n"
+		switch cmd {
+		case "SHUTDOWN", "SHUTDOWN NOSAVE":
+			return
+		case "SHUTDOWN SAVE":
+			reply = "-ERR Errors trying to SHUTDOWN. Check logs.\r\n"
+		}
+
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *shutdownServer) dial() (*Client, error) {
+	opts, err := NewOptions("gredis://" + s.l.Addr().String())
+	if err != nil {
+		return nil, err
+	}
+
+	return Dial(opts)
+}
+
+func TestClose(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newShutdownServer()
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	client, err := s.dial()
+	Expect(err).ToNot(HaveOccurred())
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.Close()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		Expect(err).ToNot(HaveOccurred())
+	}
+	Expect(client.Close()).To(Succeed())
+
+	_, err = client.Ping()
+	Expect(err).To(Equal(ErrClosed))
+	Expect(client.Pipeline(NewCmd(PingCommand))).To(Equal(ErrClosed))
+	Expect(client.Send(PingCommand)).To(Equal(ErrClosed))
+	Expect(client.Flush()).To(Equal(ErrClosed))
+	_, err = client.Receive()
+	Expect(err).To(Equal(ErrClosed))
+}
+
+func TestCloseInterruptsBlockedCommand(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(map[string]time.Duration{"BLPOP": 5 * time.Second})
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := client.Do([]byte("BLPOP"), []byte("list"), []byte("0"))
+		blocked <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	within(t, time.Second, func() {
+		err = client.Close()
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	select {
+	case err := <-blocked:
+		Expect(err).To(Equal(ErrClosed))
+	case <-time.After(time.Second):
+		t.Fatal("blocked command was not interrupted")
+	}
+}
+
+func TestLowLevelConcurrentClose(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newShutdownServer()
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	client, err := s.dial()
+	Expect(err).ToNot(HaveOccurred())
+
+	// closed flag is read under the lock, so the race detector stays silent
+	var sendErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if sendErr = client.Send(PingCommand); sendErr != nil {
+				return
+			}
+			if _, err := client.Receive(); err != nil {
+				return
+			}
+		}
+	}()
+
+	Expect(client.Close()).To(Succeed())
+	wg.Wait()
+	if sendErr != nil {
+		Expect(sendErr).To(Equal(ErrClosed))
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newShutdownServer()
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	// error reply keeps the client open
+	client, err := s.dial()
+	Expect(err).ToNot(HaveOccurred())
+	err = client.ShutdownSave()
+	Expect(err).To(HaveOccurred())
+	Expect(err.Error()).To(HavePrefix("ERR Errors trying to SHUTDOWN"))
+	_, err = client.Ping()
+	Expect(err).ToNot(HaveOccurred())
+
+	// dropped connection is the expected reply
+	Expect(client.ShutdownNoSave()).To(Succeed())
+	_, err = client.Ping()
+	Expect(err).To(Equal(ErrClosed))
+	Expect(client.Shutdown()).To(Equal(ErrClosed))
+
+	client, err = s.dial()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client.Shutdown()).To(Succeed())
+	Expect(client.Close()).To(Succeed())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	Expect(s.received).To(Equal([]string{"SHUTDOWN SAVE", "PING", "SHUTDOWN NOSAVE", "SHUTDOWN"}))
+}
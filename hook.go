@@ -0,0 +1,194 @@
+package gredis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/valery-barysok/resp"
+)
+
+// Cmd is a single command sent to GRedis server together with its outcome
+type Cmd struct {
+	Name []byte
+	Args [][]byte
+	// Timeout overrides read timeout of the command when not zero, NoTimeout disables it
+	Timeout time.Duration
+
+	// Reply is set when command succeeds
+	Reply *resp.Message
+	// Err is set when command fails or GRedis server replies with error
+	Err error
+	// Duration is the time spent by client on the wire for the command or the whole pipeline
+	Duration time.Duration
+
+	// each receives elements of array reply as they are read instead of Reply, see KeysEach
+	each func(item []byte)
+	// ctx interrupts the command when done, see DoContext
+	ctx context.Context
+	// internal commands of the client, e.g. failover pings, are not counted in Stats
+	internal bool
+}
+
+var cmdPool = sync.Pool{
+	New: func() interface{} {
+		return &Cmd{}
+	},
+}
+
+// NewCmd returns command with specified name and arguments
+func NewCmd(name []byte, args ...[]byte) *Cmd {
+	return &Cmd{Name: name, Args: args}
+}
+
+// ProcessFunc executes a single command
+type ProcessFunc func(cmd *Cmd) error
+
+// ProcessPipelineFunc executes a batch of commands
+type ProcessPipelineFunc func(cmds []*Cmd) error
+
+// Hook wraps command execution. A hook receives the next function of the chain and returns function that
+// runs code before and after calling next, modifies commands or short-circuits the call by not calling
+// next at all.
+//
+// Commands sent by Do and high level API are reused once the call returns, so hooks must copy name and
+// arguments they want to keep.
+type Hook interface {
+	ProcessHook(next ProcessFunc) ProcessFunc
+	ProcessPipelineHook(next ProcessPipelineFunc) ProcessPipelineFunc
+}
+
+// AddHook appends hook to the chain. The first added hook is the outermost one. Every command including
+// ones sent by high level API, e.g. `LPush` or `HGet`, goes through the chain. Hooks can be added while the
+// client is in use, commands already in flight keep going through the previous chain.
+func (client *Client) AddHook(hook Hook) {
+	client.hooksMu.Lock()
+	defer client.hooksMu.Unlock()
+
+	client.hooks = append(client.hooks, hook)
+
+	process := ProcessFunc(client.process)
+	processPipeline := ProcessPipelineFunc(client.processPipeline)
+	for i := len(client.hooks) - 1; i >= 0; i-- {
+		process = client.hooks[i].ProcessHook(process)
+		processPipeline = client.hooks[i].ProcessPipelineHook(processPipeline)
+	}
+
+	client.processHook = process
+	client.processPipelineHook = processPipeline
+}
+
+// Process executes cmd through the hook chain
+func (client *Client) Process(cmd *Cmd) error {
+	client.hooksMu.RLock()
+	processHook := client.processHook
+	client.hooksMu.RUnlock()
+
+	if processHook == nil {
+		return client.process(cmd)
+	}
+
+	return processHook(cmd)
+}
+
+// Pipeline sends all cmds to GRedis server at once and then receives their replies. Reply or error of every
+// command is stored in it.
+//
+// Returns the first error.
+func (client *Client) Pipeline(cmds ...*Cmd) error {
+	client.hooksMu.RLock()
+	processPipelineHook := client.processPipelineHook
+	client.hooksMu.RUnlock()
+
+	if processPipelineHook == nil {
+		return client.processPipeline(cmds)
+	}
+
+	return processPipelineHook(cmds)
+}
+
+func (client *Client) process(cmd *Cmd) error {
+	if cmd.each != nil {
+		return client.processEach(cmd)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	start := time.Now()
+	if cmd.Err = client.ensureConn(); cmd.Err == nil {
+		stop := client.watch(cmd.ctx)
+		cmd.Reply, cmd.Err = client.do(client.readTimeout(cmd), cmd.Name, cmd.Args...)
+
+		if stop() {
+			// connection was closed to interrupt the command
+			client.broken = true
+			if cmd.Err != nil {
+				cmd.Reply, cmd.Err = nil, cmd.ctx.Err()
+			}
+		}
+	}
+	cmd.Duration = time.Since(start)
+	if !cmd.internal {
+		client.stats.record(cmd)
+	}
+
+	return cmd.Err
+}
+
+func (client *Client) processPipeline(cmds []*Cmd) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	start := time.Now()
+	defer func() {
+		elapsed := time.Since(start)
+		for _, cmd := range cmds {
+			cmd.Duration = elapsed
+			client.stats.record(cmd)
+		}
+	}()
+
+	if err := client.ensureConn(); err != nil {
+		return setCmdsErr(cmds, err)
+	}
+
+	client.conn.SetWriteDeadline(deadline(client.opts.WriteTimeout))
+	for _, cmd := range cmds {
+		if err := client.w.WriteCmd(cmd.Name, cmd.Args...); err != nil {
+			return setCmdsErr(cmds, client.checkTimeout("write", err))
+		}
+	}
+
+	if err := client.flush(); err != nil {
+		return setCmdsErr(cmds, err)
+	}
+
+	var firstErr error
+	for i, cmd := range cmds {
+		cmd.Reply, cmd.Err = client.receive(client.readTimeout(cmd))
+		if cmd.Err == nil {
+			continue
+		}
+
+		if firstErr == nil {
+			firstErr = cmd.Err
+		}
+		if isNetError(cmd.Err) {
+			// the stream is broken so the rest of replies will never arrive
+			setCmdsErr(cmds[i:], cmd.Err)
+			break
+		}
+	}
+
+	return firstErr
+}
+
+func setCmdsErr(cmds []*Cmd, err error) error {
+	for _, cmd := range cmds {
+		cmd.Reply = nil
+		cmd.Err = err
+	}
+
+	return err
+}
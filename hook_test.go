@@ -0,0 +1,141 @@
+package gredis
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+type recordHook struct {
+	cmds      []string
+	pipelines [][]string
+	reject    string
+}
+
+var errRejected = errors.New("rejected by hook")
+
+func (h *recordHook) ProcessHook(next ProcessFunc) ProcessFunc {
+	return func(cmd *Cmd) error {
+		if string(cmd.Name) == h.reject {
+			cmd.Err = errRejected
+			return cmd.Err
+		}
+
+		err := next(cmd)
+		h.cmds = append(h.cmds, string(cmd.Name))
+		return err
+	}
+}
+
+func (h *recordHook) ProcessPipelineHook(next ProcessPipelineFunc) ProcessPipelineFunc {
+	return func(cmds []*Cmd) error {
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, string(cmd.Name))
+		}
+		h.pipelines = append(h.pipelines, names)
+
+		return next(cmds)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	outer := &recordHook{}
+	inner := &recordHook{reject: "DEL"}
+	client.AddHook(outer)
+	client.AddHook(inner)
+
+	_, err = client.LPush("list_key", "a")
+	Expect(err).ToNot(HaveOccurred())
+
+	_, err = client.HGet("dict_key", "field")
+	Expect(err).ToNot(HaveOccurred())
+
+	_, err = client.Del("list_key")
+	Expect(err).To(Equal(errRejected))
+
+	Expect(inner.cmds).To(Equal([]string{"LPUSH", "HGET"}))
+	Expect(outer.cmds).To(Equal([]string{"LPUSH", "HGET", "DEL"}))
+
+	exists, err := client.Exists("list_key")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(exists).To(Equal(1))
+
+	cmds := []*Cmd{
+		NewCmd(PingCommand),
+		NewCmd(LLenCommand, []byte("list_key")),
+		NewCmd(GetCommand, []byte("list_key")),
+	}
+	err = client.Pipeline(cmds...)
+	Expect(err).To(HaveOccurred())
+
+	Expect(outer.pipelines).To(Equal([][]string{{"PING", "LLEN", "GET"}}))
+	Expect(cmds[0].Err).ToNot(HaveOccurred())
+	Expect(cmds[0].Reply.String()).To(Equal("PONG"))
+	Expect(cmds[1].Reply.Int()).To(Equal(1))
+	Expect(cmds[2].Err).To(Equal(err))
+	Expect(cmds[2].Duration).To(BeNumerically(">", 0))
+}
+
+// passHook calls the next function only
+type passHook struct{}
+
+func (passHook) ProcessHook(next ProcessFunc) ProcessFunc {
+	return next
+}
+
+func (passHook) ProcessPipelineHook(next ProcessPipelineFunc) ProcessPipelineFunc {
+	return next
+}
+
+func TestAddHookWhileInUse(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	done := make(chan error)
+	go func() {
+		for i := 0; i < 100; i++ {
+			if _, err := client.Ping(); err != nil {
+				done <- err
+				return
+			}
+			if err := client.Pipeline(NewCmd(PingCommand)); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	for i := 0; i < 100; i++ {
+		client.AddHook(passHook{})
+	}
+
+	Expect(<-done).ToNot(HaveOccurred())
+}
@@ -0,0 +1,308 @@
+package gredis
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const defaultPageSize = 1000
+
+// ListIterator pages through elements of a list with `LRANGE`, so only one page of elements is kept in memory.
+// Elements added or removed while iterating shift positions of the rest, so they may be skipped or seen twice.
+//
+//	it := client.LRangeIter("mylist", 0, -1, 100)
+//	for it.Next() {
+//		process(it.Value())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type ListIterator struct {
+	client   *Client
+	key      string
+	next     int
+	stop     int
+	pageSize int
+
+	resolved bool
+	page     [][]byte
+	pos      int
+	value    []byte
+	err      error
+	done     bool
+}
+
+// LRangeIter returns iterator over elements of the list stored at key between start and stop inclusive,
+// see LRange for meaning of negative offsets. Elements are fetched by pageSize, 1000 by default.
+func (client *Client) LRangeIter(key string, start int, stop int, pageSize int) *ListIterator {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	return &ListIterator{client: client, key: key, next: start, stop: stop, pageSize: pageSize}
+}
+
+// Next advances iterator to the next element, false is returned when there are no more elements or an
+// error occurred
+func (it *ListIterator) Next() bool {
+	if it.pos < len(it.page) {
+		it.value = it.page[it.pos]
+		it.pos++
+		return true
+	}
+
+	if it.done || !it.fetch() {
+		it.value = nil
+		return false
+	}
+
+	return it.Next()
+}
+
+// Value returns the current element
+func (it *ListIterator) Value() []byte {
+	return it.value
+}
+
+// Err returns the first error occurred while iterating
+func (it *ListIterator) Err() error {
+	return it.err
+}
+
+func (it *ListIterator) fetch() bool {
+	if !it.resolved {
+		if err := it.resolve(); err != nil {
+			it.err = err
+			return false
+		}
+	}
+
+	if it.next > it.stop {
+		it.done = true
+		return false
+	}
+
+	last := it.next + it.pageSize - 1
+	if last > it.stop {
+		last = it.stop
+	}
+
+	page, err := it.client.LRange(it.key, it.next, last)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	if len(page) == 0 || len(page) < last-it.next+1 {
+		// the list got shorter
+		it.done = true
+	}
+	it.next = last + 1
+	it.page = page
+	it.pos = 0
+
+	return len(page) > 0
+}
+
+// resolve turns negative offsets into positive ones, so pages can be computed
+func (it *ListIterator) resolve() error {
+	it.resolved = true
+	if it.next >= 0 && it.stop >= 0 {
+		return nil
+	}
+
+	n, err := it.client.LLen(it.key)
+	if err != nil {
+		return err
+	}
+
+	if it.next < 0 {
+		it.next += n
+		if it.next < 0 {
+			it.next = 0
+		}
+	}
+	if it.stop < 0 {
+		it.stop += n
+	}
+
+	return nil
+}
+
+// LRangeEach calls fn for every element of the list stored at key between start and stop inclusive, fetching
+// them by pageSize, see LRangeIter. Iteration stops at the first error returned by fn.
+func (client *Client) LRangeEach(key string, start int, stop int, pageSize int, fn func(value []byte) error) error {
+	it := client.LRangeIter(key, start, stop, pageSize)
+	for it.Next() {
+		if err := fn(it.Value()); err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
+}
+
+// KeysEach calls fn for every key matching pattern, see Keys. GRedis server replies with all keys at once,
+// but keys are decoded one by one as they are read from the connection, so the reply is never held in
+// memory. key is only valid until fn returns. Iteration stops at the first error returned by fn, the rest
+// of the reply is read and dropped.
+//
+// The reply is read through a connection dialed for the call, so other commands are not blocked while it
+// streams and fn may send commands through the client.
+func (client *Client) KeysEach(pattern string, fn func(key []byte) error) error {
+	var fnErr error
+	cmd := NewCmd(KeysCommand, []byte(pattern))
+	cmd.each = func(key []byte) {
+		if fnErr == nil {
+			fnErr = fn(key)
+		}
+	}
+
+	if err := client.Process(cmd); err != nil {
+		return err
+	}
+
+	return fnErr
+}
+
+// processEach runs cmd with doEach on a connection of its own, so mu is not held while cmd.each runs
+func (client *Client) processEach(cmd *Cmd) error {
+	client.mu.Lock()
+	closed, addr := client.isClosed(), client.addr
+	client.mu.Unlock()
+
+	start := time.Now()
+	if closed {
+		cmd.Err = ErrClosed
+	} else {
+		var tmp *Client
+		if tmp, cmd.Err = client.dial(addr); cmd.Err == nil {
+			stop := tmp.watch(cmd.ctx)
+			cmd.Err = tmp.doEach(client.readTimeout(cmd), cmd)
+			if stop() && cmd.Err != nil {
+				cmd.Err = cmd.ctx.Err()
+			}
+			tmp.conn.Close()
+		}
+	}
+	cmd.Duration = time.Since(start)
+	if !cmd.internal {
+		client.stats.record(cmd)
+	}
+
+	return cmd.Err
+}
+
+// doEach sends cmd and passes elements of its array reply to cmd.each as they are read. The reply is read
+// straight from the connection past r, so the connection must carry no other commands: processEach runs it
+// on a connection dialed for cmd, where r has nothing buffered.
+func (client *Client) doEach(timeout time.Duration, cmd *Cmd) error {
+	if err := client.send(cmd.Name, cmd.Args...); err != nil {
+		return err
+	}
+
+	client.conn.SetReadDeadline(deadline(timeout))
+	each := func(item []byte) {
+		cmd.each(item)
+		// the timeout limits waiting for the server, not the time spent in cmd.each
+		client.conn.SetReadDeadline(deadline(timeout))
+	}
+
+	var r io.Reader = client.conn
+	if client.trace != nil {
+		r = io.TeeReader(r, client.trace)
+	}
+	if err := readArray(bufio.NewReader(r), each); err != nil {
+		switch err.(type) {
+		case replyError:
+			return err
+		case net.Error:
+			return client.checkTimeout("read", err)
+		}
+
+		// the rest of the reply can not be skipped
+		client.broken = true
+		client.conn.Close()
+		return err
+	}
+
+	return nil
+}
+
+// replyError is error reply of GRedis server
+type replyError string
+
+func (e replyError) Error() string {
+	return string(e)
+}
+
+var errInvalidReply = errors.New("invalid reply")
+
+// readArray reads array of bulk strings passing every element to fn, buffer of element is reused. Error
+// reply is returned as replyError.
+func readArray(r *bufio.Reader, fn func(item []byte)) error {
+	line, err := readLine(r)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(line) > 0 && line[0] == '-':
+		return replyError(line[1:])
+	case len(line) == 0 || line[0] != '*':
+		return errInvalidReply
+	}
+
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil {
+		return errInvalidReply
+	}
+
+	var item []byte
+	for i := 0; i < n; i++ {
+		if line, err = readLine(r); err != nil {
+			return err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return errInvalidReply
+		}
+
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil || size < -1 {
+			return errInvalidReply
+		}
+		if size == -1 {
+			fn(nil)
+			continue
+		}
+
+		if cap(item) < size+2 {
+			item = make([]byte, size+2)
+		}
+		item = item[:size+2]
+		if _, err := io.ReadFull(r, item); err != nil {
+			return err
+		}
+
+		fn(item[:size])
+	}
+
+	return nil
+}
+
+// readLine reads line without trailing CRLF
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, errInvalidReply
+	}
+
+	return line[:len(line)-2], nil
+}
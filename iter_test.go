@@ -0,0 +1,217 @@
+package gredis
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+func TestLRangeIter(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	for i := 0; i < 25; i++ {
+		_, err := client.RPush("list", strconv.Itoa(i))
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	collect := func(start int, stop int, pageSize int) []string {
+		var res []string
+		it := client.LRangeIter("list", start, stop, pageSize)
+		for it.Next() {
+			res = append(res, string(it.Value()))
+		}
+		Expect(it.Err()).ToNot(HaveOccurred())
+		return res
+	}
+
+	all, err := client.LRange("list", 0, -1)
+	Expect(err).ToNot(HaveOccurred())
+	var expected []string
+	for _, v := range all {
+		expected = append(expected, string(v))
+	}
+
+	Expect(collect(0, -1, 10)).To(Equal(expected))
+	Expect(collect(0, -1, 5)).To(Equal(expected))
+	Expect(collect(0, 100, 7)).To(Equal(expected))
+	Expect(collect(-5, -1, 2)).To(Equal(expected[20:]))
+	Expect(collect(3, 12, 4)).To(Equal(expected[3:13]))
+	Expect(collect(30, -1, 4)).To(BeEmpty())
+
+	it := client.LRangeIter("missing", 0, -1, 10)
+	Expect(it.Next()).To(BeFalse())
+	Expect(it.Err()).ToNot(HaveOccurred())
+
+	// iteration stops at the first error of callback
+	errStop := errors.New("stop")
+	cnt := 0
+	err = client.LRangeEach("list", 0, -1, 10, func(value []byte) error {
+		cnt++
+		if cnt == 12 {
+			return errStop
+		}
+		return nil
+	})
+	Expect(err).To(Equal(errStop))
+	Expect(cnt).To(Equal(12))
+
+	var keys []string
+	err = client.KeysEach("^list$", func(key []byte) error {
+		keys = append(keys, string(key))
+		return nil
+	})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(keys).To(Equal([]string{"list"}))
+}
+
+// keysServer replies to `KEYS` with the same keys and to the rest of commands with +OK, `KEYS bad` fails
+type keysServer struct {
+	l    net.Listener
+	keys []string
+}
+
+func (s *keysServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		n, err := readBenchInt(r)
+		if err != nil {
+			return
+		}
+
+		args := make([]string, n)
+		for i := range args {
+			size, err := readBenchInt(r)
+			if err != nil {
+				return
+			}
+
+			arg := make([]byte, size+2)
+			if _, err := io.ReadFull(r, arg); err != nil {
+				return
+			}
+			args[i] = string(arg[:size])
+		}
+
+		reply := "+OK\r\n"
+		switch {
+		case args[0] == "KEYS" && args[1] == "bad":
+			reply = "-ERR invalid pattern\r\n"
+		case args[0] == "KEYS":
+			reply = "*" + strconv.Itoa(len(s.keys)) + "\r\n"
+			for _, key := range s.keys {
+				reply += "$" + strconv.Itoa(len(key)) + "\r\n" + key + "\r\n"
+			}
+		}
+
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func TestKeysEachStreams(t *testing.T) {
+	RegisterTestingT(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+	defer l.Close()
+
+	s := &keysServer{l: l, keys: []string{"a", strings.Repeat("b", 10000), "c"}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+
+	opts, err := NewOptions("gredis://" + l.Addr().String())
+	Expect(err).ToNot(HaveOccurred())
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	var keys []string
+	err = client.KeysEach(".*", func(key []byte) error {
+		keys = append(keys, string(key))
+		return nil
+	})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(keys).To(Equal(s.keys))
+
+	// the rest of the reply is dropped after the first error, so the next command gets its own reply
+	errStop := errors.New("stop")
+	cnt := 0
+	err = client.KeysEach(".*", func(key []byte) error {
+		cnt++
+		return errStop
+	})
+	Expect(err).To(Equal(errStop))
+	Expect(cnt).To(Equal(1))
+	Expect(client.Set("key", "value")).To(BeTrue())
+
+	err = client.KeysEach("bad", func(key []byte) error {
+		return nil
+	})
+	Expect(err).To(HaveOccurred())
+	Expect(client.Set("key", "value")).To(BeTrue())
+	Expect(client.Stats().Commands["KEYS"].Calls).To(Equal(uint64(3)))
+
+	// fn can use the client while keys stream
+	var values []bool
+	within(t, 5*time.Second, func() {
+		err = client.KeysEach(".*", func(key []byte) error {
+			ok, err := client.Set(string(key), "value")
+			values = append(values, ok)
+			return err
+		})
+	})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(values).To(Equal([]bool{true, true, true}))
+
+	// time spent in fn does not count towards read timeout
+	slowOpts, err := NewOptions("gredis://" + l.Addr().String() + "?read_timeout=50ms")
+	Expect(err).ToNot(HaveOccurred())
+	slow, err := Dial(slowOpts)
+	Expect(err).ToNot(HaveOccurred())
+	defer slow.Close()
+
+	cnt = 0
+	err = slow.KeysEach(".*", func(key []byte) error {
+		cnt++
+		time.Sleep(60 * time.Millisecond)
+		return nil
+	})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(cnt).To(Equal(3))
+
+	Expect(client.Close()).To(Succeed())
+	err = client.KeysEach(".*", func(key []byte) error {
+		return nil
+	})
+	Expect(err).To(Equal(ErrClosed))
+}
@@ -0,0 +1,307 @@
+package gredis
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	mrand "math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLockTTL           = 10 * time.Second
+	defaultLockRetryDelay    = 10 * time.Millisecond
+	defaultLockMaxRetryDelay = 500 * time.Millisecond
+)
+
+var (
+	// ErrLockNotAcquired is returned by TryAcquire and Acquire when lock is held by someone else
+	ErrLockNotAcquired = errors.New("lock is not acquired")
+	// ErrLockNotHeld is returned by Release and Extend when lock has expired or was taken over
+	ErrLockNotHeld = errors.New("lock is not held")
+	// ErrLockHeld is returned by TryAcquire and Acquire when lock is already held by this owner
+	ErrLockHeld = errors.New("lock is already held")
+	// ErrLockExpiring is returned by Release when the rest of the lease is too short to delete the key
+	// safely. The owner has given up the lock, but others can take it only once the key expires.
+	ErrLockExpiring = errors.New("lock is left to expire")
+)
+
+var (
+	pxArg = []byte("PX")
+	nxArg = []byte("NX")
+)
+
+// LockOptions provides settings for Lock
+type LockOptions struct {
+	// TTL is the lease time of lock, 10 seconds by default
+	TTL time.Duration
+	// RetryDelay is the initial delay between attempts of Acquire, 10 milliseconds by default. The delay is
+	// doubled after every attempt up to MaxRetryDelay.
+	RetryDelay time.Duration
+	// MaxRetryDelay limits delay between attempts of Acquire, 500 milliseconds by default
+	MaxRetryDelay time.Duration
+	// RefreshInterval enables extending the lease by TTL every RefreshInterval while lock is held, it should
+	// be noticeably less than TTL
+	RefreshInterval time.Duration
+}
+
+// Lock is a lock shared by processes using the same GRedis server. The lock is a key holding random token of
+// its owner and expiring after TTL, so the lock is freed even if its owner dies.
+//
+// GRedis server has neither scripts nor compare-and-set commands, so Release and Extend can not check the
+// token and change the key atomically. They check the token with GET and then send DEL or PEXPIRE as a
+// separate command. If the lease expired between them and another owner took the lock, the change would be
+// applied to lock of the new owner. To close this window Lock tracks the lease locally, starting it before
+// the command that set it was sent, and sends the change only while the rest of the lease is longer than
+// round-trip time of the check. The change is safe as long as it reaches the server within that time and
+// clocks of the client and the server run at the same rate. Keep TTL well above round-trip time.
+type Lock struct {
+	client *Client
+	key    string
+	opts   LockOptions
+	now    func() time.Time
+
+	mu    sync.Mutex
+	token []byte
+	// expires is the end of the lease measured by local clock, it is never later than on the server
+	expires time.Time
+	stop    chan struct{}
+	done    chan struct{}
+	lost    chan struct{}
+}
+
+// NewLock returns lock stored at key
+func NewLock(client *Client, key string, opts LockOptions) *Lock {
+	if opts.TTL == 0 {
+		opts.TTL = defaultLockTTL
+	}
+	if opts.RetryDelay == 0 {
+		opts.RetryDelay = defaultLockRetryDelay
+	}
+	if opts.MaxRetryDelay == 0 {
+		opts.MaxRetryDelay = defaultLockMaxRetryDelay
+	}
+
+	return &Lock{client: client, key: key, opts: opts, now: time.Now}
+}
+
+// TryAcquire makes a single attempt to take the lock. ErrLockNotAcquired is returned if lock is held by
+// someone else and ErrLockHeld if it is already held by this owner, release it first.
+func (l *Lock) TryAcquire() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.token != nil {
+		return ErrLockHeld
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	start := l.now()
+	ms := strconv.FormatInt(int64(l.opts.TTL/time.Millisecond), 10)
+	msg, err := l.client.Do(SetCommand, []byte(l.key), token, pxArg, []byte(ms), nxArg)
+	if err != nil {
+		return err
+	}
+	if msg.IsNil() {
+		return ErrLockNotAcquired
+	}
+
+	l.token = token
+	l.expires = start.Add(l.opts.TTL)
+	l.lost = make(chan struct{})
+	if l.opts.RefreshInterval > 0 {
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.refresh(l.stop, l.done, l.lost)
+	}
+
+	return nil
+}
+
+// Acquire takes the lock retrying with exponential backoff until ctx is done
+func (l *Lock) Acquire(ctx context.Context) error {
+	delay := l.opts.RetryDelay
+	for {
+		err := l.TryAcquire()
+		if err != ErrLockNotAcquired {
+			return err
+		}
+
+		// jitter spreads attempts of competing processes
+		wait := delay/2 + time.Duration(mrand.Int63n(int64(delay/2)+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if delay > l.opts.MaxRetryDelay {
+			delay = l.opts.MaxRetryDelay
+		}
+	}
+}
+
+// Release frees the lock if it is still held by this owner, ErrLockNotHeld is returned otherwise. When the
+// rest of the lease is too short to delete the key safely, see Lock, the key is left to expire on its own
+// and ErrLockExpiring is returned. The lock can be acquired again after any outcome.
+func (l *Lock) Release() error {
+	l.stopRefresh()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.token == nil {
+		return ErrLockNotHeld
+	}
+	token := l.token
+	l.token = nil
+
+	safe, err := l.check(token)
+	if err != nil {
+		return err
+	}
+	if !safe {
+		return ErrLockExpiring
+	}
+
+	_, err = l.client.Del(l.key)
+	return err
+}
+
+// Extend resets lease of the lock to ttl if it is still held by this owner, ErrLockNotHeld is returned
+// otherwise or when the rest of the lease is too short to extend it safely, see Lock. After ErrLockNotHeld
+// the lock can be acquired again.
+func (l *Lock) Extend(ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.extend(ttl)
+}
+
+// Lost returns channel closed when automatic refresh fails to extend the lease, see
+// LockOptions.RefreshInterval
+func (l *Lock) Lost() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lost
+}
+
+// extend resets lease of the lock, the token is dropped when the lock turns out to be lost, so it can be
+// taken again
+func (l *Lock) extend(ttl time.Duration) error {
+	if l.token == nil {
+		return ErrLockNotHeld
+	}
+
+	err := l.pexpire(ttl)
+	if err == ErrLockNotHeld {
+		l.token = nil
+	}
+
+	return err
+}
+
+func (l *Lock) pexpire(ttl time.Duration) error {
+	safe, err := l.check(l.token)
+	if err != nil {
+		return err
+	}
+	if !safe {
+		return ErrLockNotHeld
+	}
+
+	start := l.now()
+	updated, err := l.client.PExpire(l.key, int(ttl/time.Millisecond))
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrLockNotHeld
+	}
+	l.expires = start.Add(ttl)
+
+	return nil
+}
+
+// check reports whether the rest of the lease is long enough to change the key by the next command.
+// ErrLockNotHeld is returned when the lease has ended or key does not hold token.
+func (l *Lock) check(token []byte) (bool, error) {
+	start := l.now()
+	if !start.Before(l.expires) {
+		return false, ErrLockNotHeld
+	}
+
+	current, err := l.client.Get(l.key)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(current, token) {
+		return false, ErrLockNotHeld
+	}
+
+	now := l.now()
+	rtt := now.Sub(start)
+
+	return now.Add(rtt).Before(l.expires), nil
+}
+
+func (l *Lock) refresh(stop, done, lost chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(l.opts.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		l.mu.Lock()
+		err := l.extend(l.opts.TTL)
+		l.mu.Unlock()
+
+		if err != nil {
+			close(lost)
+			return
+		}
+	}
+}
+
+func (l *Lock) stopRefresh() {
+	l.mu.Lock()
+	stop, done := l.stop, l.done
+	l.stop, l.done = nil, nil
+	l.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// randomToken returns 32 random hex digits
+func randomToken() ([]byte, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, hex.EncodedLen(len(b)))
+	hex.Encode(token, b)
+
+	return token, nil
+}
@@ -0,0 +1,208 @@
+package gredis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+func TestLock(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	first := NewLock(client, "lock", LockOptions{TTL: 100 * time.Millisecond})
+	second := NewLock(client, "lock", LockOptions{TTL: 100 * time.Millisecond})
+
+	Expect(first.TryAcquire()).To(Succeed())
+	Expect(second.TryAcquire()).To(Equal(ErrLockNotAcquired))
+
+	Expect(first.Extend(time.Second)).To(Succeed())
+	Expect(first.Release()).To(Succeed())
+	Expect(first.Release()).To(Equal(ErrLockNotHeld))
+
+	// the lease expires and the lock is taken over by another owner
+	Expect(first.TryAcquire()).To(Succeed())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	Expect(second.Acquire(ctx)).To(Succeed())
+
+	Expect(first.Extend(time.Second)).To(Equal(ErrLockNotHeld))
+	Expect(first.Release()).To(Equal(ErrLockNotHeld))
+	Expect(client.Exists("lock")).To(Equal(1))
+
+	Expect(second.Release()).To(Succeed())
+	Expect(client.Exists("lock")).To(Equal(0))
+}
+
+func TestLockAcquireDeadline(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	owner := NewLock(client, "lock", LockOptions{TTL: time.Minute})
+	Expect(owner.TryAcquire()).To(Succeed())
+	defer owner.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	other := NewLock(client, "lock", LockOptions{})
+	Expect(other.Acquire(ctx)).To(Equal(context.DeadlineExceeded))
+}
+
+func TestLockRefresh(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	owner := NewLock(client, "lock", LockOptions{TTL: 100 * time.Millisecond, RefreshInterval: 30 * time.Millisecond})
+	Expect(owner.TryAcquire()).To(Succeed())
+
+	// the lease is extended while lock is held
+	time.Sleep(300 * time.Millisecond)
+	other := NewLock(client, "lock", LockOptions{})
+	Expect(other.TryAcquire()).To(Equal(ErrLockNotAcquired))
+
+	// refresh reports the lock lost once it is taken over
+	_, err = client.Del("lock")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(other.TryAcquire()).To(Succeed())
+
+	select {
+	case <-owner.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lost lock was not reported")
+	}
+
+	Expect(owner.Release()).To(Equal(ErrLockNotHeld))
+	Expect(other.Release()).To(Succeed())
+}
+
+func TestLockAcquireWhileHeld(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	lock := NewLock(client, "lock", LockOptions{TTL: time.Minute, RefreshInterval: time.Second})
+	Expect(lock.TryAcquire()).To(Succeed())
+	done := lock.done
+
+	// the refresher of the first acquisition is kept
+	Expect(lock.TryAcquire()).To(Equal(ErrLockHeld))
+	Expect(lock.Acquire(context.Background())).To(Equal(ErrLockHeld))
+	Expect(lock.done).To(Equal(done))
+}
+
+func TestLockLeaseEnding(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	lock := NewLock(client, "lock", LockOptions{TTL: time.Minute})
+	Expect(lock.TryAcquire()).To(Succeed())
+
+	// the check takes 600ms close to the end of the lease, so the rest of the lease is shorter than
+	// round-trip time and the key is not changed
+	start := time.Now()
+	clock := []time.Duration{59 * time.Second, 59*time.Second + 600*time.Millisecond}
+	lock.expires = start.Add(time.Minute)
+	lock.now = func() time.Time {
+		d := clock[0]
+		clock = append(clock[1:], clock[0])
+		return start.Add(d)
+	}
+
+	Expect(lock.Release()).To(Equal(ErrLockExpiring))
+	Expect(client.Exists("lock")).To(Equal(1))
+	Expect(lock.Release()).To(Equal(ErrLockNotHeld))
+
+	_, err = client.Del("lock")
+	Expect(err).ToNot(HaveOccurred())
+
+	Expect(lock.TryAcquire()).To(Succeed())
+	clock = []time.Duration{59 * time.Second, 59*time.Second + 600*time.Millisecond}
+	lock.expires = start.Add(time.Minute)
+	Expect(lock.Extend(time.Minute)).To(Equal(ErrLockNotHeld))
+	Expect(client.Exists("lock")).To(Equal(1))
+	Expect(lock.Release()).To(Equal(ErrLockNotHeld))
+
+	_, err = client.Del("lock")
+	Expect(err).ToNot(HaveOccurred())
+}
+
+func TestLockExtendLost(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	lock := NewLock(client, "lock", LockOptions{TTL: time.Minute})
+	Expect(lock.TryAcquire()).To(Succeed())
+
+	// the lease has ended, so Extend drops the token the same way automatic refresh does
+	lock.expires = time.Now().Add(-time.Second)
+	Expect(lock.Extend(time.Minute)).To(Equal(ErrLockNotHeld))
+	Expect(lock.token).To(BeNil())
+
+	Expect(lock.Extend(time.Minute)).To(Equal(ErrLockNotHeld))
+	Expect(lock.TryAcquire()).To(Succeed())
+}
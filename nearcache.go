@@ -0,0 +1,274 @@
+package gredis
+
+import (
+	"bytes"
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valery-barysok/resp"
+)
+
+const defaultNearCacheMaxBytes = 1 << 20
+
+// NearCacheOptions provides settings for NearCache
+type NearCacheOptions struct {
+	// MaxBytes limits total size of cached keys, fields and values, least recently used entries are evicted
+	// when it is exceeded, 1 MiB by default
+	MaxBytes int
+	// TTL returns how long replies for key are cached, zero disables caching of key. Every key is cached for
+	// a second by default.
+	TTL func(key string) time.Duration
+}
+
+// NearCacheStats is a snapshot of near cache statistics
+type NearCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int
+}
+
+type nearEntry struct {
+	id      string
+	key     string
+	msg     *resp.Message
+	size    int
+	expires time.Time
+}
+
+// NearCache is an in-process LRU cache of `GET` and `HGET` replies. It is a Hook, so it is enabled with
+// AddHook and serves replies of all methods sending these commands.
+//
+// Cached entries are invalidated when the same client writes the key with `SET`, `DEL`, `HSET` or `HDEL`.
+// Writes made by other clients or by other commands are not tracked, so TTL bounds time stale replies may be
+// served. Cached replies are shared, so values returned by `Get` and `HGet` must not be modified.
+type NearCache struct {
+	opts NearCacheOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	// byKey indexes entries by key for invalidation
+	byKey map[string]map[string]*list.Element
+	// gen is incremented by every invalidation, so replies fetched before it are not cached
+	gen   uint64
+	bytes int
+	stats NearCacheStats
+}
+
+// NewNearCache returns near cache with specified options
+func NewNearCache(opts NearCacheOptions) *NearCache {
+	if opts.MaxBytes == 0 {
+		opts.MaxBytes = defaultNearCacheMaxBytes
+	}
+	if opts.TTL == nil {
+		opts.TTL = func(string) time.Duration { return time.Second }
+	}
+
+	return &NearCache{
+		opts:    opts,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		byKey:   make(map[string]map[string]*list.Element),
+	}
+}
+
+// ProcessHook serves `GET` and `HGET` from cache and invalidates entries written by cmd
+func (c *NearCache) ProcessHook(next ProcessFunc) ProcessFunc {
+	return func(cmd *Cmd) error {
+		id, key, ok := nearEntryID(cmd)
+		if !ok {
+			err := next(cmd)
+			c.invalidateCmd(cmd)
+			return err
+		}
+
+		ttl := c.opts.TTL(key)
+		if ttl <= 0 {
+			return next(cmd)
+		}
+
+		if msg := c.get(id); msg != nil {
+			cmd.Reply = msg
+			return nil
+		}
+
+		c.mu.Lock()
+		gen := c.gen
+		c.mu.Unlock()
+
+		if err := next(cmd); err != nil {
+			return err
+		}
+
+		c.set(id, key, cmd, ttl, gen)
+		return nil
+	}
+}
+
+// ProcessPipelineHook invalidates entries written by cmds, replies of pipelines are not cached
+func (c *NearCache) ProcessPipelineHook(next ProcessPipelineFunc) ProcessPipelineFunc {
+	return func(cmds []*Cmd) error {
+		err := next(cmds)
+		for _, cmd := range cmds {
+			c.invalidateCmd(cmd)
+		}
+		return err
+	}
+}
+
+// Invalidate removes all cached replies of key
+func (c *NearCache) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, el := range c.byKey[key] {
+		c.remove(el)
+	}
+}
+
+// Stats returns snapshot of near cache statistics
+func (c *NearCache) Stats() NearCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.bytes
+
+	return stats
+}
+
+func (c *NearCache) get(id string) *resp.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[id]
+	if !ok {
+		c.stats.Misses++
+		return nil
+	}
+
+	e := el.Value.(*nearEntry)
+	if c.now().After(e.expires) {
+		c.remove(el)
+		c.stats.Misses++
+		return nil
+	}
+
+	c.lru.MoveToFront(el)
+	c.stats.Hits++
+
+	return e.msg
+}
+
+func (c *NearCache) set(id string, key string, cmd *Cmd, ttl time.Duration, gen uint64) {
+	size := len(id) + len(cmd.Reply.BulkString())
+	if size > c.opts.MaxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.gen != gen {
+		// key may have been written while reply was in flight
+		return
+	}
+
+	if el, ok := c.entries[id]; ok {
+		c.remove(el)
+	}
+
+	e := &nearEntry{id: id, key: key, msg: cmd.Reply, size: size, expires: c.now().Add(ttl)}
+	el := c.lru.PushFront(e)
+	c.entries[id] = el
+	if c.byKey[key] == nil {
+		c.byKey[key] = make(map[string]*list.Element)
+	}
+	c.byKey[key][id] = el
+	c.bytes += size
+
+	for c.bytes > c.opts.MaxBytes {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *NearCache) remove(el *list.Element) {
+	e := el.Value.(*nearEntry)
+
+	c.lru.Remove(el)
+	delete(c.entries, e.id)
+	delete(c.byKey[e.key], e.id)
+	if len(c.byKey[e.key]) == 0 {
+		delete(c.byKey, e.key)
+	}
+	c.bytes -= e.size
+}
+
+// invalidateCmd removes entries written by cmd, it is called even if cmd fails because the write may have
+// been applied before the connection broke
+func (c *NearCache) invalidateCmd(cmd *Cmd) {
+	var ids []string
+	var keys [][]byte
+
+	switch {
+	case bytes.EqualFold(cmd.Name, SetCommand):
+		if len(cmd.Args) > 0 {
+			keys = cmd.Args[:1]
+		}
+	case bytes.EqualFold(cmd.Name, DelCommand):
+		keys = cmd.Args
+	case bytes.EqualFold(cmd.Name, HSetCommand):
+		if len(cmd.Args) > 1 {
+			ids = append(ids, hgetEntryID(cmd.Args[0], cmd.Args[1]))
+		}
+	case bytes.EqualFold(cmd.Name, HDelCommand):
+		for i := 1; i < len(cmd.Args); i++ {
+			ids = append(ids, hgetEntryID(cmd.Args[0], cmd.Args[i]))
+		}
+	default:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, key := range keys {
+		for _, el := range c.byKey[string(key)] {
+			c.remove(el)
+		}
+	}
+	for _, id := range ids {
+		if el, ok := c.entries[id]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+// nearEntryID returns id of cache entry for cmd and the key it belongs to, false is returned for commands
+// which are not cached
+func nearEntryID(cmd *Cmd) (string, string, bool) {
+	switch {
+	case bytes.EqualFold(cmd.Name, GetCommand) && len(cmd.Args) == 1:
+		return "g\x00" + string(cmd.Args[0]), string(cmd.Args[0]), true
+	case bytes.EqualFold(cmd.Name, HGetCommand) && len(cmd.Args) == 2:
+		return hgetEntryID(cmd.Args[0], cmd.Args[1]), string(cmd.Args[0]), true
+	}
+
+	return "", "", false
+}
+
+// hgetEntryID returns id of HGET entry, key is prefixed with its length because both key and field may
+// contain any bytes
+func hgetEntryID(key []byte, field []byte) string {
+	return "h\x00" + strconv.Itoa(len(key)) + ":" + string(key) + string(field)
+}
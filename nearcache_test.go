@@ -0,0 +1,108 @@
+package gredis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+func TestNearCache(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	near := NewNearCache(NearCacheOptions{
+		MaxBytes: 64,
+		TTL: func(key string) time.Duration {
+			if key == "nocache" {
+				return 0
+			}
+			return time.Minute
+		},
+	})
+	now := time.Now()
+	near.now = func() time.Time { return now }
+
+	// server sees only commands passed through near cache
+	server := &recordHook{}
+	client.AddHook(near)
+	client.AddHook(server)
+
+	_, err = client.Set("flag", "on")
+	Expect(err).ToNot(HaveOccurred())
+
+	Expect(client.Get("flag")).To(Equal([]byte("on")))
+	Expect(client.Get("flag")).To(Equal([]byte("on")))
+	Expect(server.cmds).To(Equal([]string{"SET", "GET"}))
+	Expect(near.Stats()).To(Equal(NearCacheStats{Hits: 1, Misses: 1, Entries: 1, Bytes: 8}))
+
+	// writes of the same client invalidate cached replies
+	_, err = client.Set("flag", "off")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client.Get("flag")).To(Equal([]byte("off")))
+
+	_, err = client.Del("flag")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client.Get("flag")).To(BeNil())
+
+	_, err = client.HSet("hash", "f", "v1")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client.HGet("hash", "f")).To(Equal([]byte("v1")))
+	Expect(client.HGet("hash", "f")).To(Equal([]byte("v1")))
+
+	_, err = client.HSet("hash", "f", "v2")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client.HGet("hash", "f")).To(Equal([]byte("v2")))
+
+	_, err = client.HDel("hash", "f")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client.HGet("hash", "f")).To(BeNil())
+
+	// entries expire after TTL
+	server.cmds = nil
+	Expect(client.HGet("hash", "f")).To(BeNil())
+	now = now.Add(2 * time.Minute)
+	Expect(client.HGet("hash", "f")).To(BeNil())
+	Expect(server.cmds).To(Equal([]string{"HGET"}))
+
+	// keys with zero TTL are not cached
+	server.cmds = nil
+	client.Get("nocache")
+	client.Get("nocache")
+	Expect(server.cmds).To(Equal([]string{"GET", "GET"}))
+
+	// least recently used entries are evicted when MaxBytes is exceeded
+	_, err = client.Set("big", strings.Repeat("x", 50))
+	Expect(err).ToNot(HaveOccurred())
+	client.Get("flag")
+	client.Get("big")
+
+	stats := near.Stats()
+	Expect(stats.Bytes).To(BeNumerically("<=", 64))
+	Expect(stats.Evictions).To(BeNumerically(">", 0))
+
+	server.cmds = nil
+	client.Get("big")
+	Expect(server.cmds).To(BeEmpty())
+}
+
+func TestNearCacheHGetEntryID(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(hgetEntryID([]byte("a\x00b"), []byte("c"))).ToNot(Equal(hgetEntryID([]byte("a"), []byte("b\x00c"))))
+	Expect(hgetEntryID([]byte("a1:"), []byte("b"))).ToNot(Equal(hgetEntryID([]byte("a"), []byte("1:b"))))
+	Expect(hgetEntryID([]byte("a"), []byte("b"))).To(Equal(hgetEntryID([]byte("a"), []byte("b"))))
+}
@@ -3,9 +3,12 @@ package gredis
 import (
 	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"net"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,8 +20,23 @@ const (
 	defaultWriteTimeout = 2 * time.Second
 )
 
+// NoTimeout disables read timeout of a command, see Options.CommandTimeouts and Cmd.Timeout
+const NoTimeout time.Duration = -1
+
 var errInvalidURLFormat = errors.New("invalid URL format")
 
+// urlParams lists query parameters supported by NewOptions
+var urlParams = map[string]bool{
+	"db":            true,
+	"password":      true,
+	"dial_timeout":  true,
+	"read_timeout":  true,
+	"write_timeout": true,
+	"pool_size":     true,
+	"trace":         true,
+	"client_name":   true,
+}
+
 // Options provides setting for client connection to GRedis server
 type Options struct {
 	Host          string
@@ -29,6 +47,34 @@ type Options struct {
 	ReadTimeout   time.Duration
 	WriteTimeout  time.Duration
 	TraceProtocol bool
+	// ClientName identifies the client in protocol trace lines
+	ClientName string
+	// PoolSize is the maximum number of idle connections kept by Pool unless PoolOptions.MaxIdle is set
+	PoolSize int
+
+	// CommandTimeouts overrides ReadTimeout for commands by their upper case name, e.g. "KEYS". NoTimeout
+	// disables read timeout of command. Blocking commands `BLPOP`, `BRPOP` and `BRPOPLPUSH` have no read
+	// timeout unless it is set here.
+	CommandTimeouts map[string]time.Duration
+
+	// TraceWriter receives protocol trace when set, os.Stdout is used if only TraceProtocol is enabled.
+	// Every line is prefixed with connection id and remote address and `AUTH` password is redacted.
+	TraceWriter io.Writer
+	// TraceMaxLen truncates traced lines longer than it, 0 means 256 and negative value disables truncation.
+	TraceMaxLen int
+
+	// Addrs lists all servers of multi-host URL in HOST:PORT form, Host and Port hold the first of them.
+	Addrs []string
+	// RandomAddrs makes Dial try Addrs in random order instead of the listed one.
+	RandomAddrs bool
+	// FailoverInterval enables pinging of the current server every FailoverInterval and switching to the next
+	// server of Addrs when it does not reply.
+	FailoverInterval time.Duration
+	// OnFailover is called with address of the new server after failover.
+	OnFailover func(addr string)
+
+	// Codec encodes and decodes values of typed helpers, e.g. SetValue and GetValue, JSONCodec by default.
+	Codec Codec
 }
 
 // NewOptions supported URLs are in any of these formats:
@@ -37,7 +83,20 @@ type Options struct {
 //	gredis://[:PASSWORD@]HOST[:PORT][/DATABASE]
 //	gredis://[:PASSWORD@]HOST[:PORT][?db=DATABASE]
 //	gredis://HOST[:PORT]/DATABASE[?password=PASSWORD]
+//
+// Several servers can be listed separated by comma, e.g. gredis://HOST1[:PORT1],HOST2[:PORT2]/DATABASE
+//
+// Other settings are set by query parameters, unknown parameters are rejected:
+//
+//	dial_timeout=DURATION  Timeout, e.g. 10s
+//	read_timeout=DURATION  ReadTimeout, e.g. 500ms
+//	write_timeout=DURATION WriteTimeout, e.g. 500ms
+//	pool_size=SIZE         PoolSize
+//	trace=BOOL             TraceProtocol, e.g. true
+//	client_name=NAME       ClientName
 func NewOptions(rawURL string) (*Options, error) {
+	rawURL, hosts := splitHosts(rawURL)
+
 	u, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, errInvalidURLFormat
@@ -53,21 +112,58 @@ func NewOptions(rawURL string) (*Options, error) {
 		WriteTimeout: defaultWriteTimeout,
 	}
 
-	opts.Host, opts.Port, err = net.SplitHostPort(u.Host)
-	if err != nil {
-		opts.Host = u.Host
-		opts.Port = defaultPort
+	opts.Host, opts.Port = splitHostPort(u.Host)
+
+	for _, host := range hosts {
+		opts.Addrs = append(opts.Addrs, net.JoinHostPort(splitHostPort(host)))
 	}
-	if opts.Host == "" {
-		opts.Host = defaultHost
+
+	query := u.Query()
+	for name := range query {
+		if !urlParams[name] {
+			return nil, fmt.Errorf("unknown URL parameter: %s", name)
+		}
 	}
 
-	opts.Password = u.Query().Get("password")
+	opts.Password = query.Get("password")
 	if u.User != nil {
 		opts.Password, _ = u.User.Password()
 	}
 
-	db := u.Query().Get("db")
+	timeouts := []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"dial_timeout", &opts.Timeout},
+		{"read_timeout", &opts.ReadTimeout},
+		{"write_timeout", &opts.WriteTimeout},
+	}
+	for _, timeout := range timeouts {
+		if v := query.Get(timeout.name); v != "" {
+			*timeout.dst, err = time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", timeout.name, v)
+			}
+		}
+	}
+
+	if v := query.Get("pool_size"); v != "" {
+		opts.PoolSize, err = strconv.Atoi(v)
+		if err != nil || opts.PoolSize < 0 {
+			return nil, fmt.Errorf("invalid pool_size: %s", v)
+		}
+	}
+
+	if v := query.Get("trace"); v != "" {
+		opts.TraceProtocol, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trace: %s", v)
+		}
+	}
+
+	opts.ClientName = query.Get("client_name")
+
+	db := query.Get("db")
 	if db != "" {
 		opts.DB, err = strconv.Atoi(db)
 		if err != nil {
@@ -85,3 +181,126 @@ func NewOptions(rawURL string) (*Options, error) {
 
 	return &opts, nil
 }
+
+// splitHosts extracts comma separated list of hosts from rawURL. It returns rawURL with the first host only
+// and the list, or unchanged rawURL and nil when rawURL has a single host.
+func splitHosts(rawURL string) (string, []string) {
+	i := strings.Index(rawURL, "://")
+	if i < 0 {
+		return rawURL, nil
+	}
+
+	start := i + len("://")
+	end := len(rawURL)
+	if j := strings.IndexAny(rawURL[start:], "/?#"); j >= 0 {
+		end = start + j
+	}
+
+	authority := rawURL[start:end]
+	if j := strings.LastIndex(authority, "@"); j >= 0 {
+		start += j + 1
+		authority = authority[j+1:]
+	}
+
+	if !strings.Contains(authority, ",") {
+		return rawURL, nil
+	}
+
+	hosts := strings.Split(authority, ",")
+	return rawURL[:start] + hosts[0] + rawURL[end:], hosts
+}
+
+// splitHostPort splits HOST[:PORT] filling in defaults, brackets of IPv6 host without port are removed
+func splitHostPort(hostport string) (string, string) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+		port = defaultPort
+	}
+	if host == "" {
+		host = defaultHost
+	}
+
+	return host, port
+}
+
+// URL returns canonical URL of options in gredis://[:PASSWORD@]HOST:PORT[,HOST:PORT...][/DATABASE][?PARAMS]
+// form, NewOptions parses it back into equal options. Only parameters differing from defaults are written,
+// settings which can not be written in URL are omitted.
+func (opts Options) URL() string {
+	return opts.url(opts.Password)
+}
+
+// Redacted returns URL of options with password replaced by "xxxxx", so it is safe for logs
+func (opts Options) Redacted() string {
+	if opts.Password == "" {
+		return opts.URL()
+	}
+
+	return opts.url("xxxxx")
+}
+
+// String returns redacted URL of options, see Redacted. It is defined on value, so passwords are not
+// printed when Options are formatted by value or embedded into other structs.
+func (opts Options) String() string {
+	return opts.Redacted()
+}
+
+func (opts Options) url(password string) string {
+	u := url.URL{Scheme: "gredis"}
+
+	if password != "" {
+		u.User = url.UserPassword("", password)
+	}
+
+	// single address is kept in Host and Port only
+	if len(opts.Addrs) > 1 {
+		u.Host = strings.Join(opts.Addrs, ",")
+	} else {
+		u.Host = net.JoinHostPort(opts.Host, opts.Port)
+	}
+
+	if opts.DB != 0 {
+		u.Path = "/" + strconv.Itoa(opts.DB)
+	}
+
+	query := url.Values{}
+	if opts.Timeout != defaultTimeout {
+		query.Set("dial_timeout", opts.Timeout.String())
+	}
+	if opts.ReadTimeout != defaultReadTimeout {
+		query.Set("read_timeout", opts.ReadTimeout.String())
+	}
+	if opts.WriteTimeout != defaultWriteTimeout {
+		query.Set("write_timeout", opts.WriteTimeout.String())
+	}
+	if opts.PoolSize != 0 {
+		query.Set("pool_size", strconv.Itoa(opts.PoolSize))
+	}
+	if opts.TraceProtocol {
+		query.Set("trace", "true")
+	}
+	if opts.ClientName != "" {
+		query.Set("client_name", opts.ClientName)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// addresses returns list of servers in order Dial should try them
+func (opts *Options) addresses() []string {
+	if len(opts.Addrs) == 0 {
+		return []string{net.JoinHostPort(opts.Host, opts.Port)}
+	}
+
+	addrs := append([]string(nil), opts.Addrs...)
+	if opts.RandomAddrs {
+		for i := range addrs {
+			j := rand.Intn(i + 1)
+			addrs[i], addrs[j] = addrs[j], addrs[i]
+		}
+	}
+
+	return addrs
+}
@@ -0,0 +1,215 @@
+package gredis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultEnvPrefix = "GREDIS"
+
+// optionsConfig holds settings read from environment or config file, nil fields are not set. Settings are
+// applied in this order, so later ones win: defaults, URL, the rest of fields.
+type optionsConfig struct {
+	URL             *string             `json:"url"`
+	Host            *string             `json:"host"`
+	Port            *string             `json:"port"`
+	DB              *int                `json:"db"`
+	Password        *string             `json:"password"`
+	Timeout         *duration           `json:"timeout"`
+	ReadTimeout     *duration           `json:"read_timeout"`
+	WriteTimeout    *duration           `json:"write_timeout"`
+	TraceProtocol   *bool               `json:"trace_protocol"`
+	CommandTimeouts map[string]duration `json:"command_timeouts"`
+}
+
+// duration is time.Duration written as "1m30s" in config files
+type duration time.Duration
+
+func (d *duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration: %s", text)
+	}
+
+	*d = duration(v)
+	return nil
+}
+
+func (c *optionsConfig) options() (*Options, error) {
+	rawURL := "gredis://" + defaultHost
+	if c.URL != nil {
+		rawURL = *c.URL
+	}
+
+	opts, err := NewOptions(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// explicit host or port replaces all servers of multi-host URL
+	if c.Host != nil {
+		opts.Host, opts.Addrs = *c.Host, nil
+	}
+	if c.Port != nil {
+		opts.Port, opts.Addrs = *c.Port, nil
+	}
+	if c.DB != nil {
+		opts.DB = *c.DB
+	}
+	if c.Password != nil {
+		opts.Password = *c.Password
+	}
+	if c.Timeout != nil {
+		opts.Timeout = time.Duration(*c.Timeout)
+	}
+	if c.ReadTimeout != nil {
+		opts.ReadTimeout = time.Duration(*c.ReadTimeout)
+	}
+	if c.WriteTimeout != nil {
+		opts.WriteTimeout = time.Duration(*c.WriteTimeout)
+	}
+	if c.TraceProtocol != nil {
+		opts.TraceProtocol = *c.TraceProtocol
+	}
+	if len(c.CommandTimeouts) > 0 {
+		opts.CommandTimeouts = make(map[string]time.Duration, len(c.CommandTimeouts))
+		for name, timeout := range c.CommandTimeouts {
+			opts.CommandTimeouts[name] = time.Duration(timeout)
+		}
+	}
+
+	return opts, nil
+}
+
+// OptionsFromEnv returns options read from environment variables named with prefix, GREDIS by default:
+//
+//	GREDIS_URL            URL in any format supported by NewOptions, gredis://localhost by default
+//	GREDIS_HOST           overrides host of URL
+//	GREDIS_PORT           overrides port of URL
+//	GREDIS_DB             overrides database of URL
+//	GREDIS_PASSWORD       overrides password of URL
+//	GREDIS_TIMEOUT        dial timeout, e.g. 10s
+//	GREDIS_READ_TIMEOUT   read timeout, e.g. 500ms
+//	GREDIS_WRITE_TIMEOUT  write timeout, e.g. 500ms
+//	GREDIS_TRACE_PROTOCOL enables protocol trace, e.g. true
+//
+// Empty variables are ignored. Defaults are overridden by URL and URL is overridden by the rest of variables.
+func OptionsFromEnv(prefix string) (*Options, error) {
+	if prefix == "" {
+		prefix = defaultEnvPrefix
+	}
+
+	env := func(name string) (string, bool) {
+		v := os.Getenv(prefix + "_" + name)
+		return v, v != ""
+	}
+
+	var c optionsConfig
+	if v, ok := env("URL"); ok {
+		c.URL = &v
+	}
+	if v, ok := env("HOST"); ok {
+		c.Host = &v
+	}
+	if v, ok := env("PORT"); ok {
+		c.Port = &v
+	}
+	if v, ok := env("PASSWORD"); ok {
+		c.Password = &v
+	}
+	if v, ok := env("DB"); ok {
+		db, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_DB: %s", prefix, v)
+		}
+		c.DB = &db
+	}
+	if v, ok := env("TRACE_PROTOCOL"); ok {
+		trace, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_TRACE_PROTOCOL: %s", prefix, v)
+		}
+		c.TraceProtocol = &trace
+	}
+
+	timeouts := []struct {
+		name string
+		dst  **duration
+	}{
+		{"TIMEOUT", &c.Timeout},
+		{"READ_TIMEOUT", &c.ReadTimeout},
+		{"WRITE_TIMEOUT", &c.WriteTimeout},
+	}
+	for _, timeout := range timeouts {
+		if v, ok := env(timeout.name); ok {
+			d := new(duration)
+			if err := d.UnmarshalText([]byte(v)); err != nil {
+				return nil, fmt.Errorf("invalid %s_%s: %s", prefix, timeout.name, v)
+			}
+			*timeout.dst = d
+		}
+	}
+
+	return c.options()
+}
+
+// UnmarshalText sets options from URL in any format supported by NewOptions, so Options can be read from
+// text config formats, e.g. YAML or TOML
+func (opts *Options) UnmarshalText(text []byte) error {
+	o, err := NewOptions(string(text))
+	if err != nil {
+		return err
+	}
+
+	*opts = *o
+	return nil
+}
+
+// UnmarshalJSON sets options from JSON string holding URL, see UnmarshalText, or from JSON object:
+//
+//	{
+//		"url": "gredis://localhost/1",
+//		"host": "localhost",
+//		"port": "16379",
+//		"db": 1,
+//		"password": "secret",
+//		"timeout": "1m",
+//		"read_timeout": "2s",
+//		"write_timeout": "2s",
+//		"trace_protocol": false,
+//		"command_timeouts": {"KEYS": "30s"}
+//	}
+//
+// All fields are optional, unknown fields are rejected. Precedence is the same as for OptionsFromEnv.
+func (opts *Options) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var rawURL string
+		if err := json.Unmarshal(data, &rawURL); err != nil {
+			return err
+		}
+		return opts.UnmarshalText([]byte(rawURL))
+	}
+
+	var c optionsConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return err
+	}
+
+	o, err := c.options()
+	if err != nil {
+		return err
+	}
+
+	*opts = *o
+	return nil
+}
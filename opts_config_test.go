@@ -0,0 +1,129 @@
+package gredis
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func setEnv(env map[string]string) func() {
+	for name, value := range env {
+		os.Setenv(name, value)
+	}
+
+	return func() {
+		for name := range env {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	RegisterTestingT(t)
+
+	// defaults
+	opts, err := OptionsFromEnv("GREDIS_TEST")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(opts.Host).To(Equal(defaultHost))
+	Expect(opts.Port).To(Equal(defaultPort))
+	Expect(opts.ReadTimeout).To(Equal(defaultReadTimeout))
+
+	// URL overrides defaults and the rest of variables override URL
+	unset := setEnv(map[string]string{
+		"GREDIS_TEST_URL":            "gredis://:urlpass@host1,host2:6380/3",
+		"GREDIS_TEST_PORT":           "6381",
+		"GREDIS_TEST_PASSWORD":       "envpass",
+		"GREDIS_TEST_READ_TIMEOUT":   "500ms",
+		"GREDIS_TEST_TRACE_PROTOCOL": "true",
+		"GREDIS_TEST_WRITE_TIMEOUT":  "",
+	})
+	defer unset()
+
+	opts, err = OptionsFromEnv("GREDIS_TEST")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(opts.Host).To(Equal("host1"))
+	Expect(opts.Port).To(Equal("6381"))
+	Expect(opts.Addrs).To(BeNil())
+	Expect(opts.DB).To(Equal(3))
+	Expect(opts.Password).To(Equal("envpass"))
+	Expect(opts.Timeout).To(Equal(defaultTimeout))
+	Expect(opts.ReadTimeout).To(Equal(500 * time.Millisecond))
+	Expect(opts.WriteTimeout).To(Equal(defaultWriteTimeout))
+	Expect(opts.TraceProtocol).To(BeTrue())
+
+	failCases := map[string]string{
+		"GREDIS_TEST_DB":             "one",
+		"GREDIS_TEST_TIMEOUT":        "10",
+		"GREDIS_TEST_TRACE_PROTOCOL": "sometimes",
+		"GREDIS_TEST_URL":            "redis://localhost",
+	}
+	for name, value := range failCases {
+		unset := setEnv(map[string]string{name: value})
+		_, err := OptionsFromEnv("GREDIS_TEST")
+		Expect(err).To(HaveOccurred(), name)
+		unset()
+	}
+}
+
+func TestOptionsUnmarshalJSON(t *testing.T) {
+	RegisterTestingT(t)
+
+	var config struct {
+		Cache  Options
+		Store  Options
+		Absent Options
+	}
+
+	err := json.Unmarshal([]byte(`{
+		"cache": "gredis://cache:6380/1",
+		"store": {
+			"url": "gredis://store/2",
+			"db": 4,
+			"read_timeout": "5s",
+			"command_timeouts": {"KEYS": "30s"}
+		},
+		"absent": null
+	}`), &config)
+	Expect(err).ToNot(HaveOccurred())
+
+	Expect(config.Cache.Host).To(Equal("cache"))
+	Expect(config.Cache.Port).To(Equal("6380"))
+	Expect(config.Cache.DB).To(Equal(1))
+	Expect(config.Cache.ReadTimeout).To(Equal(defaultReadTimeout))
+
+	Expect(config.Store.Host).To(Equal("store"))
+	Expect(config.Store.DB).To(Equal(4))
+	Expect(config.Store.ReadTimeout).To(Equal(5 * time.Second))
+	Expect(config.Store.WriteTimeout).To(Equal(defaultWriteTimeout))
+	Expect(config.Store.CommandTimeouts).To(Equal(map[string]time.Duration{"KEYS": 30 * time.Second}))
+
+	Expect(config.Absent).To(Equal(Options{}))
+
+	failCases := []string{
+		`"redis://localhost"`,
+		`{"hots": "localhost"}`,
+		`{"read_timeout": 5}`,
+		`{"read_timeout": "5 seconds"}`,
+		`{"url": "gredis://localhost/db"}`,
+	}
+	for _, data := range failCases {
+		var opts Options
+		Expect(json.Unmarshal([]byte(data), &opts)).ToNot(Succeed(), data)
+	}
+}
+
+func TestOptionsUnmarshalText(t *testing.T) {
+	RegisterTestingT(t)
+
+	var opts Options
+	Expect(opts.UnmarshalText([]byte("gredis://:password@host:6380/5"))).To(Succeed())
+	Expect(opts.Host).To(Equal("host"))
+	Expect(opts.Port).To(Equal("6380"))
+	Expect(opts.DB).To(Equal(5))
+	Expect(opts.Password).To(Equal("password"))
+
+	Expect(opts.UnmarshalText([]byte("http://host"))).ToNot(Succeed())
+}
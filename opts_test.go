@@ -1,8 +1,17 @@
 package gredis
 
 import (
-	. "github.com/onsi/gomega"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/quick"
+	"time"
+
+	. "github.com/onsi/gomega"
 )
 
 func TestOptionsWithValidUrl(t *testing.T) {
@@ -142,3 +151,259 @@ func TestOptionsWithInvalidUrl(t *testing.T) {
 		}
 	}
 }
+
+func TestOptionsWithMultipleHosts(t *testing.T) {
+	RegisterTestingT(t)
+
+	cases := []struct {
+		url   string
+		host  string
+		port  string
+		db    int
+		addrs []string
+	}{
+		{
+			"gredis://h1:16380,h2:16381/1",
+			"h1", "16380", 1,
+			[]string{"h1:16380", "h2:16381"},
+		},
+		{
+			"gredis://:password@h1,h2:16381,:16382?db=2",
+			"h1", "16379", 2,
+			[]string{"h1:16379", "h2:16381", "localhost:16382"},
+		},
+		{
+			"gredis://h1:16380",
+			"h1", "16380", 0,
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		opts, err := NewOptions(c.url)
+		if Expect(err).ToNot(HaveOccurred(), c.url) {
+			Expect(opts.Host).To(Equal(c.host), c.url)
+			Expect(opts.Port).To(Equal(c.port), c.url)
+			Expect(opts.DB).To(Equal(c.db), c.url)
+			Expect(opts.Addrs).To(Equal(c.addrs), c.url)
+		}
+	}
+
+	opts := &Options{Addrs: []string{"h1:1", "h2:2", "h3:3"}, RandomAddrs: true}
+	Expect(opts.addresses()).To(HaveLen(3))
+	Expect(opts.Addrs).To(Equal([]string{"h1:1", "h2:2", "h3:3"}))
+}
+
+func TestOptionsWithURLParams(t *testing.T) {
+	RegisterTestingT(t)
+
+	opts, err := NewOptions("gredis://localhost/1?dial_timeout=5s&read_timeout=500ms&write_timeout=1m&pool_size=16" +
+		"&trace=true&client_name=worker-1")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(opts.DB).To(Equal(1))
+	Expect(opts.Timeout).To(Equal(5 * time.Second))
+	Expect(opts.ReadTimeout).To(Equal(500 * time.Millisecond))
+	Expect(opts.WriteTimeout).To(Equal(time.Minute))
+	Expect(opts.PoolSize).To(Equal(16))
+	Expect(opts.TraceProtocol).To(BeTrue())
+	Expect(opts.ClientName).To(Equal("worker-1"))
+
+	Expect(NewPool(opts, PoolOptions{}).poolOpts.MaxIdle).To(Equal(16))
+	Expect(NewPool(opts, PoolOptions{MaxIdle: 2}).poolOpts.MaxIdle).To(Equal(2))
+
+	failureCases := []struct {
+		url string
+		err string
+	}{
+		{
+			"gredis://localhost?read_timeout=500",
+			"invalid read_timeout: 500",
+		},
+		{
+			"gredis://localhost?dial_timeout=soon",
+			"invalid dial_timeout: soon",
+		},
+		{
+			"gredis://localhost?pool_size=-1",
+			"invalid pool_size: -1",
+		},
+		{
+			"gredis://localhost?trace=maybe",
+			"invalid trace: maybe",
+		},
+		{
+			"gredis://localhost?readtimeout=1s",
+			"unknown URL parameter: readtimeout",
+		},
+	}
+
+	for _, c := range failureCases {
+		_, err := NewOptions(c.url)
+		if Expect(err).To(HaveOccurred(), c.url) {
+			Expect(err.Error()).To(Equal(c.err), c.url)
+		}
+	}
+}
+
+// urlFields are random settings which can be written in URL, Generate renders them in one of formats
+// supported by NewOptions
+type urlFields struct {
+	hosts    []string
+	db       int
+	password string
+	// params are appended to URL of any format
+	params url.Values
+	format int
+}
+
+var urlFormats = []func(f urlFields) string{
+	// gredis://HOST[:PORT][?db=DATABASE[&password=PASSWORD]]
+	func(f urlFields) string {
+		return "gredis://" + strings.Join(f.hosts, ",") + "?db=" + strconv.Itoa(f.db) + "&password=" +
+			url.QueryEscape(f.password)
+	},
+	// gredis://HOST[:PORT][?password=PASSWORD[&db=DATABASE]]
+	func(f urlFields) string {
+		return "gredis://" + strings.Join(f.hosts, ",") + "?password=" + url.QueryEscape(f.password) + "&db=" +
+			strconv.Itoa(f.db)
+	},
+	// gredis://[:PASSWORD@]HOST[:PORT][/DATABASE]
+	func(f urlFields) string {
+		return "gredis://" + url.UserPassword("", f.password).String() + "@" + strings.Join(f.hosts, ",") + "/" +
+			strconv.Itoa(f.db)
+	},
+	// gredis://[:PASSWORD@]HOST[:PORT][?db=DATABASE]
+	func(f urlFields) string {
+		return "gredis://" + url.UserPassword("", f.password).String() + "@" + strings.Join(f.hosts, ",") +
+			"?db=" + strconv.Itoa(f.db)
+	},
+	// gredis://HOST[:PORT]/DATABASE[?password=PASSWORD]
+	func(f urlFields) string {
+		return "gredis://" + strings.Join(f.hosts, ",") + "/" + strconv.Itoa(f.db) + "?password=" +
+			url.QueryEscape(f.password)
+	},
+}
+
+func (urlFields) Generate(r *rand.Rand, size int) reflect.Value {
+	const hostChars = "abcdefghijklmnopqrstuvwxyz0123456789-."
+
+	f := urlFields{
+		hosts:  make([]string, 1+r.Intn(3)),
+		db:     r.Intn(16),
+		format: r.Intn(len(urlFormats)),
+	}
+
+	for i := range f.hosts {
+		switch r.Intn(4) {
+		case 0:
+			f.hosts[i] = "[::1]"
+		case 1:
+			f.hosts[i] = "127.0.0." + strconv.Itoa(r.Intn(256))
+		default:
+			host := make([]byte, 1+r.Intn(size+1))
+			for j := range host {
+				host[j] = hostChars[r.Intn(len(hostChars))]
+			}
+			f.hosts[i] = string(host)
+		}
+
+		if r.Intn(2) == 0 {
+			f.hosts[i] += ":" + strconv.Itoa(1+r.Intn(65535))
+		}
+	}
+
+	if r.Intn(4) > 0 {
+		password := make([]rune, 1+r.Intn(size+1))
+		for i := range password {
+			password[i] = rune(' ' + r.Intn(0x3000))
+		}
+		f.password = string(password)
+	}
+
+	f.params = url.Values{}
+	if r.Intn(2) == 0 {
+		f.params.Set("read_timeout", time.Duration(r.Int63n(int64(time.Hour))).String())
+	}
+	if r.Intn(2) == 0 {
+		f.params.Set("pool_size", strconv.Itoa(r.Intn(100)))
+	}
+	if r.Intn(2) == 0 {
+		f.params.Set("trace", strconv.FormatBool(r.Intn(2) == 0))
+	}
+	if r.Intn(2) == 0 {
+		f.params.Set("client_name", f.password)
+	}
+
+	return reflect.ValueOf(f)
+}
+
+func TestOptionsURLRoundTrip(t *testing.T) {
+	RegisterTestingT(t)
+
+	roundTrip := func(f urlFields) bool {
+		rawURL := urlFormats[f.format](f)
+		if params := f.params.Encode(); params != "" {
+			if strings.Contains(rawURL, "?") {
+				rawURL += "&" + params
+			} else {
+				rawURL += "?" + params
+			}
+		}
+
+		opts, err := NewOptions(rawURL)
+		if err != nil {
+			t.Logf("%s: %v", rawURL, err)
+			return false
+		}
+
+		parsed, err := NewOptions(opts.URL())
+		if err != nil {
+			t.Logf("%s: %s: %v", rawURL, opts.URL(), err)
+			return false
+		}
+		if !reflect.DeepEqual(parsed, opts) || parsed.URL() != opts.URL() {
+			t.Logf("%s: %s: %#v != %#v", rawURL, opts.URL(), *parsed, *opts)
+			return false
+		}
+
+		redacted, err := NewOptions(opts.Redacted())
+		if err != nil {
+			t.Logf("%s: %s: %v", rawURL, opts.Redacted(), err)
+			return false
+		}
+		if opts.Password != "" && redacted.Password != "xxxxx" {
+			t.Logf("%s: %s: password is not redacted", rawURL, opts.Redacted())
+			return false
+		}
+		redacted.Password = opts.Password
+
+		return reflect.DeepEqual(redacted, opts) && opts.String() == opts.Redacted()
+	}
+
+	Expect(quick.Check(roundTrip, &quick.Config{MaxCount: 1000})).To(Succeed())
+}
+
+func TestOptionsStringRedacts(t *testing.T) {
+	RegisterTestingT(t)
+
+	opts, err := NewOptions("gredis://:secret@localhost:16379/1")
+	Expect(err).ToNot(HaveOccurred())
+
+	redacted := "gredis://:xxxxx@localhost:16379/1"
+	Expect(opts.String()).To(Equal(redacted))
+	Expect(fmt.Sprint(opts)).To(Equal(redacted))
+	Expect(fmt.Sprint(*opts)).To(Equal(redacted))
+	Expect(fmt.Sprintf("%v", *opts)).To(Equal(redacted))
+
+	// Options embedded into config structs are formatted with String too
+	type config struct {
+		Name string
+		DB   Options
+	}
+	embedded := struct{ Options }{*opts}
+	for _, v := range []interface{}{embedded, &embedded, config{Name: "app", DB: *opts}} {
+		s := fmt.Sprintf("%v %+v", v, v)
+		Expect(s).To(ContainSubstring("xxxxx"))
+		Expect(s).ToNot(ContainSubstring("secret"))
+	}
+}
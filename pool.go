@@ -0,0 +1,311 @@
+package gredis
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const defaultMaxIdle = 8
+
+var errPoolClosed = errors.New("pool is closed")
+
+// PoolOptions provides settings for Pool
+type PoolOptions struct {
+	// MaxIdle is the maximum number of idle connections kept by pool, Options.PoolSize or 8 by default
+	MaxIdle int
+	// TestOnBorrow makes Get ping idle connection before handing it out, connections failing to reply
+	// are closed
+	TestOnBorrow bool
+	// HealthCheckInterval enables background pinging of idle connections every HealthCheckInterval
+	HealthCheckInterval time.Duration
+	// OnHealthChange is called when health state of pool changes. Calls are serialized and follow the order
+	// of changes, a slow callback delays health updates.
+	OnHealthChange func(healthy bool, err error)
+}
+
+// PoolStats is a snapshot of pool statistics
+type PoolStats struct {
+	Active int
+	Idle   int
+	Hits   uint64
+	Misses uint64
+	Failed uint64
+}
+
+type idleClient struct {
+	client *Client
+	since  time.Time
+}
+
+// Pool keeps idle connections to GRedis server for reuse. Pool is safe for concurrent use.
+type Pool struct {
+	opts     *Options
+	poolOpts PoolOptions
+
+	mu      sync.Mutex
+	idle    []idleClient
+	active  int
+	closed  bool
+	stats   PoolStats
+	healthy bool
+	latency time.Duration
+
+	// healthMu serializes health changes together with OnHealthChange calls
+	healthMu sync.Mutex
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewPool returns pool of connections to GRedis server with specified options
+func NewPool(opts *Options, poolOpts PoolOptions) *Pool {
+	if poolOpts.MaxIdle == 0 {
+		poolOpts.MaxIdle = opts.PoolSize
+	}
+	if poolOpts.MaxIdle == 0 {
+		poolOpts.MaxIdle = defaultMaxIdle
+	}
+
+	p := &Pool{
+		opts:     opts,
+		poolOpts: poolOpts,
+		healthy:  true,
+		done:     make(chan struct{}),
+	}
+
+	if poolOpts.HealthCheckInterval > 0 {
+		p.wg.Add(1)
+		go p.healthCheck(poolOpts.HealthCheckInterval)
+	}
+
+	return p
+}
+
+// Get returns idle connection or establishes a new one. Return it back to pool with Put.
+func (p *Pool) Get() (*Client, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errPoolClosed
+		}
+
+		if len(p.idle) == 0 {
+			p.stats.Misses++
+			p.active++
+			p.mu.Unlock()
+
+			client, err := Dial(p.opts)
+			if err != nil {
+				p.mu.Lock()
+				p.active--
+				p.mu.Unlock()
+
+				p.setHealth(false, err)
+				return nil, err
+			}
+
+			p.setHealth(true, nil)
+			return client, nil
+		}
+
+		ic := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.active++
+		p.mu.Unlock()
+
+		if p.poolOpts.TestOnBorrow {
+			if err := p.ping(ic.client); err != nil {
+				p.mu.Lock()
+				p.active--
+				p.mu.Unlock()
+				continue
+			}
+		}
+
+		p.mu.Lock()
+		p.stats.Hits++
+		p.mu.Unlock()
+
+		return ic.client, nil
+	}
+}
+
+// Put returns client taken by Get back to pool. The client is closed if pool already has MaxIdle idle
+// connections or is closed.
+func (p *Pool) Put(client *Client) {
+	p.mu.Lock()
+	p.active--
+	if p.closed || len(p.idle) >= p.poolOpts.MaxIdle {
+		p.mu.Unlock()
+		client.Close()
+		return
+	}
+
+	p.idle = append(p.idle, idleClient{client: client, since: time.Now()})
+	p.mu.Unlock()
+}
+
+// Close stops health checker and closes all idle connections. Connections returned later by Put are closed.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	close(p.done)
+	p.wg.Wait()
+
+	for _, ic := range idle {
+		ic.client.Close()
+	}
+}
+
+// Healthy returns false when the last health check, test on borrow or dial failed
+func (p *Pool) Healthy() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.healthy
+}
+
+// Latency returns smoothed round-trip time of health check pings
+func (p *Pool) Latency() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.latency
+}
+
+// Stats returns snapshot of pool statistics
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	stats.Active = p.active
+	stats.Idle = len(p.idle)
+
+	return stats
+}
+
+// ping checks client, tracks round-trip time and closes client that fails to reply
+func (p *Pool) ping(client *Client) error {
+	start := time.Now()
+	_, err := client.Ping()
+	rtt := time.Since(start)
+
+	if err != nil {
+		client.Close()
+
+		p.mu.Lock()
+		p.stats.Failed++
+		p.mu.Unlock()
+
+		p.setHealth(false, err)
+		return err
+	}
+
+	p.mu.Lock()
+	if p.latency == 0 {
+		p.latency = rtt
+	} else {
+		// exponentially weighted moving average smooths out spikes
+		p.latency = (p.latency*7 + rtt) / 8
+	}
+	p.mu.Unlock()
+
+	p.setHealth(true, nil)
+	return nil
+}
+
+func (p *Pool) setHealth(healthy bool, err error) {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+
+	p.mu.Lock()
+	changed := p.healthy != healthy
+	p.healthy = healthy
+	p.mu.Unlock()
+
+	if changed && p.poolOpts.OnHealthChange != nil {
+		p.poolOpts.OnHealthChange(healthy, err)
+	}
+}
+
+func (p *Pool) healthCheck(interval time.Duration) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		p.checkIdle(interval)
+	}
+}
+
+// checkIdle pings connections idle for at least interval. When there are no idle connections a new one is
+// established, so health state is kept up to date.
+func (p *Pool) checkIdle(interval time.Duration) {
+	now := time.Now()
+
+	p.mu.Lock()
+	var check []idleClient
+	idle := p.idle[:0]
+	for _, ic := range p.idle {
+		if now.Sub(ic.since) >= interval {
+			check = append(check, ic)
+		} else {
+			idle = append(idle, ic)
+		}
+	}
+	p.idle = idle
+	empty := len(idle) == 0 && len(check) == 0
+	p.active += len(check)
+	p.mu.Unlock()
+
+	if empty {
+		// dialed directly, so health checks are not counted as misses
+		client, err := Dial(p.opts)
+		if err != nil {
+			p.setHealth(false, err)
+			return
+		}
+
+		p.mu.Lock()
+		p.active++
+		p.mu.Unlock()
+
+		if p.ping(client) == nil {
+			p.Put(client)
+		} else {
+			p.mu.Lock()
+			p.active--
+			p.mu.Unlock()
+		}
+		return
+	}
+
+	for _, ic := range check {
+		if p.ping(ic.client) == nil {
+			p.Put(ic.client)
+			continue
+		}
+
+		p.mu.Lock()
+		p.active--
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,196 @@
+package gredis
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+func TestPool(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	pool := NewPool(opts, PoolOptions{
+		MaxIdle:             1,
+		TestOnBorrow:        true,
+		HealthCheckInterval: 10 * time.Millisecond,
+	})
+	defer pool.Close()
+
+	c1, err := pool.Get()
+	Expect(err).ToNot(HaveOccurred())
+	c2, err := pool.Get()
+	Expect(err).ToNot(HaveOccurred())
+
+	stats := pool.Stats()
+	Expect(stats.Active).To(Equal(2))
+	Expect(stats.Misses).To(Equal(uint64(2)))
+
+	pool.Put(c1)
+	pool.Put(c2)
+
+	stats = pool.Stats()
+	Expect(stats.Active).To(Equal(0))
+	Expect(stats.Idle).To(Equal(1))
+
+	c, err := pool.Get()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(c).To(BeIdenticalTo(c1))
+	Expect(pool.Stats().Hits).To(Equal(uint64(1)))
+
+	// broken connection is detected on borrow and replaced with a new one
+	c.conn.Close()
+	pool.Put(c)
+
+	c, err = pool.Get()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(c).ToNot(BeIdenticalTo(c1))
+	Expect(pool.Stats().Failed).To(Equal(uint64(1)))
+
+	_, err = c.Ping()
+	Expect(err).ToNot(HaveOccurred())
+	pool.Put(c)
+
+	time.Sleep(50 * time.Millisecond)
+	Expect(pool.Healthy()).To(BeTrue())
+	Expect(pool.Latency()).To(BeNumerically(">", 0))
+}
+
+func TestPoolHealthChange(t *testing.T) {
+	RegisterTestingT(t)
+
+	opts, err := NewOptions("gredis://localhost:1")
+	Expect(err).ToNot(HaveOccurred())
+
+	changes := make(chan bool, 10)
+	pool := NewPool(opts, PoolOptions{
+		HealthCheckInterval: 10 * time.Millisecond,
+		OnHealthChange: func(healthy bool, err error) {
+			changes <- healthy
+		},
+	})
+	defer pool.Close()
+
+	select {
+	case healthy := <-changes:
+		Expect(healthy).To(BeFalse())
+	case <-time.After(5 * time.Second):
+		t.Fatal("health change was not reported")
+	}
+
+	Expect(pool.Healthy()).To(BeFalse())
+
+	pool.Close()
+	_, err = pool.Get()
+	Expect(err).To(MatchError("pool is closed"))
+}
+
+func TestPoolHealthRecoversOnDial(t *testing.T) {
+	RegisterTestingT(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+	addr := l.Addr().String()
+	l.Close()
+
+	opts, err := NewOptions("gredis://" + addr)
+	Expect(err).ToNot(HaveOccurred())
+
+	p := NewPool(opts, PoolOptions{})
+	defer p.Close()
+
+	_, err = p.Get()
+	Expect(err).To(HaveOccurred())
+	Expect(p.Healthy()).To(BeFalse())
+
+	l, err = net.Listen("tcp", addr)
+	Expect(err).ToNot(HaveOccurred())
+	s := &delayServer{l: l}
+	go s.serve()
+	defer s.l.Close()
+
+	client, err := p.Get()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(p.Healthy()).To(BeTrue())
+	p.Put(client)
+}
+
+func TestPoolHealthCheckIsNotMiss(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://" + s.l.Addr().String())
+	Expect(err).ToNot(HaveOccurred())
+
+	p := NewPool(opts, PoolOptions{HealthCheckInterval: 10 * time.Millisecond})
+	defer p.Close()
+
+	Eventually(func() int { return p.Stats().Idle }).Should(Equal(1))
+	time.Sleep(50 * time.Millisecond)
+
+	stats := p.Stats()
+	Expect(stats.Misses).To(Equal(uint64(0)))
+	Expect(stats.Active).To(Equal(0))
+	Expect(p.Healthy()).To(BeTrue())
+}
+
+func TestPoolHealthChangeOrder(t *testing.T) {
+	RegisterTestingT(t)
+
+	opts, err := NewOptions("gredis://localhost:1")
+	Expect(err).ToNot(HaveOccurred())
+
+	var (
+		mu      sync.Mutex
+		changes []bool
+		running int32
+		overlap bool
+	)
+	pool := NewPool(opts, PoolOptions{
+		OnHealthChange: func(healthy bool, err error) {
+			if atomic.AddInt32(&running, 1) > 1 {
+				overlap = true
+			}
+			mu.Lock()
+			changes = append(changes, healthy)
+			mu.Unlock()
+			time.Sleep(time.Microsecond)
+			atomic.AddInt32(&running, -1)
+		},
+	})
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				pool.setHealth((i+j)%2 == 0, nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	Expect(overlap).To(BeFalse())
+	Expect(changes).ToNot(BeEmpty())
+	for i := 1; i < len(changes); i++ {
+		Expect(changes[i]).ToNot(Equal(changes[i-1]), "change %d", i)
+	}
+	Expect(changes[len(changes)-1]).To(Equal(pool.Healthy()))
+}
@@ -0,0 +1,546 @@
+package gredis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultVisibilityTimeout = 30 * time.Second
+	defaultMaxAttempts       = 5
+	defaultPollInterval      = 100 * time.Millisecond
+	defaultBlockTimeout      = time.Second
+)
+
+// ErrJobExpired is returned by Ack and Nack when visibility timeout of job has passed and the job was
+// already given to another worker
+var ErrJobExpired = errors.New("job visibility timeout has expired")
+
+// QueueOptions provides settings for Queue
+type QueueOptions struct {
+	// VisibilityTimeout is the time worker has to Ack or Nack job before it is requeued, 30 seconds by
+	// default
+	VisibilityTimeout time.Duration
+	// MaxAttempts is the number of failed deliveries after which job is moved to dead letter list, 5 by
+	// default
+	MaxAttempts int
+	// BlockTimeout is the longest time Dequeue blocks in `BRPOPLPUSH` before it promotes delayed jobs, it is
+	// rounded up to seconds, 1 second by default
+	BlockTimeout time.Duration
+	// PollInterval is the delay before Run retries after an error of GRedis server, 100 milliseconds by
+	// default
+	PollInterval time.Duration
+	// OnError is called by Run with errors it can not return: errors of Dequeue, Ack, Nack and Requeue. job
+	// is nil for errors not related to a single job. Errors are dropped when it is not set.
+	OnError func(job *Job, err error)
+}
+
+// Job is a unit of work stored in Queue
+type Job struct {
+	ID   string `json:"id"`
+	Body []byte `json:"body"`
+	// Attempts is the number of failed deliveries of the job
+	Attempts int `json:"attempts"`
+	// RunAt is the time in unix milliseconds delayed job becomes available
+	RunAt int64 `json:"run_at,omitempty"`
+
+	raw        []byte
+	processing string
+}
+
+// Handler processes job dequeued by Queue.Run. Job is acknowledged when nil is returned and negatively
+// acknowledged otherwise. A panic of handler is recovered, reported to QueueOptions.OnError and the job is
+// negatively acknowledged.
+type Handler func(ctx context.Context, job *Job) error
+
+// Queue is a reliable work queue stored in GRedis server. Dequeued jobs are moved to processing list of
+// worker and stay there until they are acknowledged, so jobs of crashed workers are not lost and are
+// requeued after visibility timeout. Queue is safe for concurrent use.
+//
+// Queue named `name` uses the following keys:
+//
+//	name                   list of pending jobs
+//	name:processing:worker list of jobs taken by worker
+//	name:leases            hash of visibility deadlines of taken jobs
+//	name:delayed           hash of delayed jobs keyed by RUNAT:ID, so due jobs are found by field names
+//	name:dead              list of jobs which exceeded MaxAttempts
+type Queue struct {
+	client *Client
+	name   string
+	opts   QueueOptions
+	now    func() time.Time
+}
+
+// NewQueue returns queue stored under name. VisibilityTimeout must be at least a millisecond, leases are
+// stored with millisecond precision, other durations and MaxAttempts must not be negative.
+func NewQueue(client *Client, name string, opts QueueOptions) (*Queue, error) {
+	if opts.VisibilityTimeout == 0 {
+		opts.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.BlockTimeout == 0 {
+		opts.BlockTimeout = defaultBlockTimeout
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+
+	switch {
+	case opts.VisibilityTimeout < time.Millisecond:
+		return nil, fmt.Errorf("invalid visibility timeout: %s", opts.VisibilityTimeout)
+	case opts.MaxAttempts < 0:
+		return nil, fmt.Errorf("invalid max attempts: %d", opts.MaxAttempts)
+	case opts.BlockTimeout < 0:
+		return nil, fmt.Errorf("invalid block timeout: %s", opts.BlockTimeout)
+	case opts.PollInterval < 0:
+		return nil, fmt.Errorf("invalid poll interval: %s", opts.PollInterval)
+	}
+
+	return &Queue{client: client, name: name, opts: opts, now: time.Now}, nil
+}
+
+// Enqueue adds job with body to the queue and returns its id
+func (q *Queue) Enqueue(body []byte) (string, error) {
+	return q.EnqueueDelayed(body, 0)
+}
+
+// EnqueueDelayed adds job with body which becomes available after delay and returns its id
+func (q *Queue) EnqueueDelayed(body []byte, delay time.Duration) (string, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	job := &Job{ID: string(id), Body: body}
+	if err := q.push(job, delay); err != nil {
+		return "", err
+	}
+
+	return job.ID, nil
+}
+
+// Dequeue moves the oldest pending job to processing list of worker and returns it. Dequeue waits for a job
+// with `BRPOPLPUSH` until ctx is done, delayed jobs which became available are promoted before every wait.
+// Worker names must be unique across all processes sharing the queue.
+//
+// The wait blocks the connection of the client, so other commands sent through the same client wait for it
+// too. Run dials a dedicated connection for every worker.
+func (q *Queue) Dequeue(ctx context.Context, worker string) (*Job, error) {
+	processing := q.processingKey(worker)
+	timeout := []byte(strconv.Itoa(int((q.opts.BlockTimeout + time.Second - 1) / time.Second)))
+	for {
+		if _, err := q.Promote(); err != nil {
+			return nil, err
+		}
+
+		msg, err := q.client.DoContext(ctx, BRPopLPushCommand, []byte(q.name), []byte(processing), timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if msg.IsNil() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		job, err := decodeJob(msg.BulkString())
+		if err != nil {
+			return nil, err
+		}
+		job.processing = processing
+
+		if _, err := q.client.HSet(q.leasesKey(), job.ID, q.deadline()); err != nil {
+			return nil, err
+		}
+
+		return job, nil
+	}
+}
+
+// Ack removes job processed successfully. ErrJobExpired is returned if job was requeued because its
+// visibility timeout has passed.
+func (q *Queue) Ack(job *Job) error {
+	if err := q.take(job); err != nil {
+		return err
+	}
+
+	_, err := q.client.HDel(q.leasesKey(), job.ID)
+	return err
+}
+
+// Nack returns job which failed to be processed back to the queue after delay. Job is moved to dead letter
+// list when it has failed MaxAttempts times. ErrJobExpired is returned if job was requeued because its
+// visibility timeout has passed.
+func (q *Queue) Nack(job *Job, delay time.Duration) error {
+	if err := q.take(job); err != nil {
+		return err
+	}
+
+	if _, err := q.client.HDel(q.leasesKey(), job.ID); err != nil {
+		return err
+	}
+
+	return q.retry(job, delay)
+}
+
+// Requeue returns jobs whose visibility timeout has passed back to the queue and returns their number. Run
+// calls Requeue periodically.
+func (q *Queue) Requeue() (int, error) {
+	lists, err := q.client.Keys("^" + regexp.QuoteMeta(q.processingKey("")))
+	if err != nil {
+		return 0, err
+	}
+
+	now := q.now().UnixNano() / int64(time.Millisecond)
+	cnt := 0
+	for _, list := range lists {
+		items, err := q.client.LRange(string(list), 0, -1)
+		if err != nil {
+			return cnt, err
+		}
+
+		for _, raw := range items {
+			job, err := decodeJob(raw)
+			if err != nil {
+				return cnt, err
+			}
+
+			lease, err := q.client.HGet(q.leasesKey(), job.ID)
+			if err != nil {
+				return cnt, err
+			}
+			if lease == nil {
+				// worker died between taking the job and setting its lease, start counting from now
+				if _, err := q.client.HSet(q.leasesKey(), job.ID, q.deadline()); err != nil {
+					return cnt, err
+				}
+				continue
+			}
+
+			deadline, err := strconv.ParseInt(string(lease), 10, 64)
+			if err == nil && deadline > now {
+				continue
+			}
+
+			// only the one who removes the job from processing list requeues it
+			job.processing = string(list)
+			if err := q.take(job); err == ErrJobExpired {
+				continue
+			} else if err != nil {
+				return cnt, err
+			}
+
+			if _, err := q.client.HDel(q.leasesKey(), job.ID); err != nil {
+				return cnt, err
+			}
+			if err := q.retry(job, 0); err != nil {
+				return cnt, err
+			}
+			cnt++
+		}
+	}
+
+	return cnt, nil
+}
+
+// Promote moves delayed jobs which became available to the queue and returns their number. Dequeue calls
+// Promote before waiting for a job. Only field names of the delayed hash are read for jobs which are not due
+// yet.
+func (q *Queue) Promote() (int, error) {
+	fields, err := q.client.HKeys(q.delayedKey())
+	if err != nil {
+		return 0, err
+	}
+
+	now := q.now().UnixNano() / int64(time.Millisecond)
+	cnt := 0
+	for _, field := range fields {
+		runAt, err := parseDelayedField(string(field))
+		if err != nil {
+			return cnt, err
+		}
+		if runAt > now {
+			continue
+		}
+
+		raw, err := q.client.HGet(q.delayedKey(), string(field))
+		if err != nil {
+			return cnt, err
+		}
+		if raw == nil {
+			continue
+		}
+
+		job, err := decodeJob(raw)
+		if err != nil {
+			return cnt, err
+		}
+
+		// only the one who removes the job from delayed hash enqueues it
+		removed, err := q.client.HDel(q.delayedKey(), string(field))
+		if err != nil {
+			return cnt, err
+		}
+		if removed == 0 {
+			continue
+		}
+
+		job.RunAt = 0
+		if err := q.push(job, 0); err != nil {
+			return cnt, err
+		}
+		cnt++
+	}
+
+	return cnt, nil
+}
+
+// Len returns the number of pending jobs
+func (q *Queue) Len() (int, error) {
+	return q.client.LLen(q.name)
+}
+
+// Dead returns jobs moved to dead letter list, the most recent first
+func (q *Queue) Dead() ([]*Job, error) {
+	items, err := q.client.LRange(q.deadKey(), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(items))
+	for _, raw := range items {
+		job, err := decodeJob(raw)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// Run processes jobs with handler in workers goroutines and requeues expired jobs until ctx is done. Run
+// waits for handlers in progress to return and returns ctx.Err(). Workers are named `prefix-N`, prefix
+// must be unique across all processes sharing the queue. Every worker dequeues jobs through its own
+// connection dialed with options of the client.
+//
+// Errors of GRedis server are reported to QueueOptions.OnError and retried after PollInterval.
+func (q *Queue) Run(ctx context.Context, prefix string, workers int, handler Handler) error {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			q.work(ctx, worker, handler)
+		}(prefix + "-" + strconv.Itoa(i))
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(q.opts.VisibilityTimeout / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if _, err := q.Requeue(); err != nil {
+				q.report(nil, err)
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func (q *Queue) work(ctx context.Context, worker string, handler Handler) {
+	var wq *Queue
+	for wq == nil {
+		client, err := Dial(q.client.opts)
+		if err == nil {
+			defer client.Close()
+
+			c := *q
+			c.client = client
+			wq = &c
+			break
+		}
+
+		q.report(nil, err)
+		if !q.sleep(ctx) {
+			return
+		}
+	}
+
+	for {
+		job, err := wq.Dequeue(ctx, worker)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			q.report(nil, err)
+			if !q.sleep(ctx) {
+				return
+			}
+			continue
+		}
+
+		if q.handle(ctx, job, handler) == nil {
+			err = wq.Ack(job)
+		} else {
+			err = wq.Nack(job, 0)
+		}
+		if err != nil {
+			q.report(job, err)
+		}
+	}
+}
+
+// handle runs handler, a panic of handler is reported and returned as error
+func (q *Queue) handle(ctx context.Context, job *Job, handler Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+			q.report(job, err)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// sleep waits for PollInterval, false is returned if ctx is done first
+func (q *Queue) sleep(ctx context.Context) bool {
+	timer := time.NewTimer(q.opts.PollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (q *Queue) report(job *Job, err error) {
+	if q.opts.OnError != nil {
+		q.opts.OnError(job, err)
+	}
+}
+
+// take removes job from its processing list, ErrJobExpired is returned if it is not there
+func (q *Queue) take(job *Job) error {
+	removed, err := q.client.LRem(job.processing, 1, string(job.raw))
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrJobExpired
+	}
+
+	return nil
+}
+
+// retry counts failed delivery of job and pushes it back or to dead letter list
+func (q *Queue) retry(job *Job, delay time.Duration) error {
+	job.Attempts++
+	if job.Attempts >= q.opts.MaxAttempts {
+		raw, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.client.LPushBytes(q.deadKey(), raw)
+		return err
+	}
+
+	return q.push(job, delay)
+}
+
+func (q *Queue) push(job *Job, delay time.Duration) error {
+	if delay > 0 {
+		job.RunAt = q.now().Add(delay).UnixNano() / int64(time.Millisecond)
+	}
+
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	if delay > 0 {
+		_, err = q.client.HSetBytes(q.delayedKey(), delayedField(job), raw)
+		return err
+	}
+
+	_, err = q.client.LPushBytes(q.name, raw)
+	return err
+}
+
+func (q *Queue) deadline() string {
+	return strconv.FormatInt(q.now().Add(q.opts.VisibilityTimeout).UnixNano()/int64(time.Millisecond), 10)
+}
+
+func (q *Queue) processingKey(worker string) string {
+	return q.name + ":processing:" + worker
+}
+
+func (q *Queue) leasesKey() string {
+	return q.name + ":leases"
+}
+
+func (q *Queue) delayedKey() string {
+	return q.name + ":delayed"
+}
+
+func (q *Queue) deadKey() string {
+	return q.name + ":dead"
+}
+
+// delayedField returns field of job in delayed hash, it starts with due time so Promote skips jobs which are
+// not due without reading them
+func delayedField(job *Job) string {
+	return strconv.FormatInt(job.RunAt, 10) + ":" + job.ID
+}
+
+// parseDelayedField returns due time of job stored in field of delayed hash
+func parseDelayedField(field string) (int64, error) {
+	i := strings.IndexByte(field, ':')
+	if i < 0 {
+		return 0, fmt.Errorf("invalid delayed job field: %s", field)
+	}
+
+	runAt, err := strconv.ParseInt(field[:i], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid delayed job field: %s", field)
+	}
+
+	return runAt, nil
+}
+
+func decodeJob(raw []byte) (*Job, error) {
+	job := &Job{}
+	if err := json.Unmarshal(raw, job); err != nil {
+		return nil, err
+	}
+	job.raw = raw
+
+	return job, nil
+}
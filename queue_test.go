@@ -0,0 +1,314 @@
+package gredis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+func TestQueue(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	queue, err := NewQueue(client, "jobs", QueueOptions{MaxAttempts: 2, PollInterval: 10 * time.Millisecond})
+	Expect(err).ToNot(HaveOccurred())
+	now := time.Now()
+	queue.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	id1, err := queue.Enqueue([]byte("first"))
+	Expect(err).ToNot(HaveOccurred())
+	id2, err := queue.Enqueue([]byte("second"))
+	Expect(err).ToNot(HaveOccurred())
+	Expect(queue.Len()).To(Equal(2))
+
+	// jobs are dequeued in order and stay in processing list until acknowledged
+	job, err := queue.Dequeue(ctx, "w1")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(job.ID).To(Equal(id1))
+	Expect(job.Body).To(Equal([]byte("first")))
+	Expect(client.LLen("jobs:processing:w1")).To(Equal(1))
+
+	Expect(queue.Ack(job)).To(Succeed())
+	Expect(client.LLen("jobs:processing:w1")).To(Equal(0))
+	Expect(queue.Ack(job)).To(Equal(ErrJobExpired))
+
+	// failed job is retried and moved to dead letter list after MaxAttempts
+	job, err = queue.Dequeue(ctx, "w1")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(job.ID).To(Equal(id2))
+	Expect(queue.Nack(job, 0)).To(Succeed())
+
+	job, err = queue.Dequeue(ctx, "w1")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(job.ID).To(Equal(id2))
+	Expect(job.Attempts).To(Equal(1))
+	Expect(queue.Nack(job, 0)).To(Succeed())
+
+	Expect(queue.Len()).To(Equal(0))
+	dead, err := queue.Dead()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(dead).To(HaveLen(1))
+	Expect(dead[0].ID).To(Equal(id2))
+	Expect(dead[0].Attempts).To(Equal(2))
+
+	// job of crashed worker is requeued after visibility timeout
+	id3, err := queue.Enqueue([]byte("third"))
+	Expect(err).ToNot(HaveOccurred())
+	job, err = queue.Dequeue(ctx, "crashed")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(job.ID).To(Equal(id3))
+
+	Expect(queue.Requeue()).To(Equal(0))
+	now = now.Add(time.Minute)
+	Expect(queue.Requeue()).To(Equal(1))
+
+	job2, err := queue.Dequeue(ctx, "w2")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(job2.ID).To(Equal(id3))
+	Expect(job2.Attempts).To(Equal(1))
+	Expect(queue.Ack(job)).To(Equal(ErrJobExpired))
+	Expect(queue.Ack(job2)).To(Succeed())
+
+	// delayed job becomes available after delay
+	id4, err := queue.EnqueueDelayed([]byte("later"), time.Minute)
+	Expect(err).ToNot(HaveOccurred())
+
+	short, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	_, err = queue.Dequeue(short, "w1")
+	cancel()
+	Expect(err).To(Equal(context.DeadlineExceeded))
+
+	now = now.Add(2 * time.Minute)
+	job, err = queue.Dequeue(ctx, "w1")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(job.ID).To(Equal(id4))
+	Expect(queue.Ack(job)).To(Succeed())
+
+	// jobs which are not due are not read
+	_, err = queue.EnqueueDelayed([]byte("soon"), time.Minute)
+	Expect(err).ToNot(HaveOccurred())
+	_, err = queue.EnqueueDelayed([]byte("later"), time.Hour)
+	Expect(err).ToNot(HaveOccurred())
+
+	hook := &recordHook{}
+	client.AddHook(hook)
+	now = now.Add(2 * time.Minute)
+	Expect(queue.Promote()).To(Equal(1))
+	Expect(hook.cmds).To(Equal([]string{"HKEYS", "HGET", "HDEL", "LPUSH"}))
+}
+
+func TestQueueRun(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	queue, err := NewQueue(client, "jobs", QueueOptions{PollInterval: 10 * time.Millisecond})
+	Expect(err).ToNot(HaveOccurred())
+	for _, body := range []string{"a", "b", "c", "fail"} {
+		_, err := queue.Enqueue([]byte(body))
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	var mu sync.Mutex
+	var processed []string
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := func(ctx context.Context, job *Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if string(job.Body) == "fail" {
+			return errors.New("failed")
+		}
+
+		processed = append(processed, string(job.Body))
+		if len(processed) == 3 {
+			cancel()
+		}
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- queue.Run(ctx, "test", 3, handler)
+	}()
+
+	select {
+	case err := <-done:
+		Expect(err).To(Equal(context.Canceled))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+
+	Expect(processed).To(ConsistOf("a", "b", "c"))
+	keys, err := client.Keys("^jobs:processing:")
+	Expect(err).ToNot(HaveOccurred())
+	for _, key := range keys {
+		Expect(client.LLen(string(key))).To(Equal(0))
+	}
+}
+
+func TestQueueRunRecoversPanic(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var reported error
+	queue, err := NewQueue(client, "panics", QueueOptions{
+		MaxAttempts:  1,
+		PollInterval: 10 * time.Millisecond,
+		OnError: func(job *Job, err error) {
+			reported = err
+			cancel()
+		},
+	})
+	Expect(err).ToNot(HaveOccurred())
+	_, err = queue.Enqueue([]byte("boom"))
+	Expect(err).ToNot(HaveOccurred())
+
+	handler := func(ctx context.Context, job *Job) error {
+		panic("boom")
+	}
+	Expect(queue.Run(ctx, "test", 1, handler)).To(Equal(context.Canceled))
+	Expect(reported).To(MatchError("handler panic: boom"))
+
+	// the job is negatively acknowledged, so it is moved to dead letter list after MaxAttempts
+	Eventually(func() int {
+		dead, _ := queue.Dead()
+		return len(dead)
+	}).Should(Equal(1))
+}
+
+func TestQueueHandlePanic(t *testing.T) {
+	RegisterTestingT(t)
+
+	var reported *Job
+	queue, err := NewQueue(nil, "jobs", QueueOptions{
+		OnError: func(job *Job, err error) {
+			reported = job
+		},
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	job := &Job{ID: "1"}
+	err = queue.handle(context.Background(), job, func(ctx context.Context, job *Job) error {
+		panic(errors.New("boom"))
+	})
+	Expect(err).To(MatchError("handler panic: boom"))
+	Expect(reported).To(BeIdenticalTo(job))
+
+	err = queue.handle(context.Background(), job, func(ctx context.Context, job *Job) error {
+		return nil
+	})
+	Expect(err).ToNot(HaveOccurred())
+}
+
+func TestDelayedField(t *testing.T) {
+	RegisterTestingT(t)
+
+	field := delayedField(&Job{ID: "abc", RunAt: 1500000000000})
+	Expect(field).To(Equal("1500000000000:abc"))
+	Expect(parseDelayedField(field)).To(Equal(int64(1500000000000)))
+
+	for _, field := range []string{"abc", "x:abc"} {
+		_, err := parseDelayedField(field)
+		Expect(err).To(MatchError("invalid delayed job field: " + field))
+	}
+}
+
+func TestQueueOptions(t *testing.T) {
+	RegisterTestingT(t)
+
+	invalid := []QueueOptions{
+		{VisibilityTimeout: -time.Second},
+		{VisibilityTimeout: time.Nanosecond},
+		{MaxAttempts: -1},
+		{BlockTimeout: -time.Second},
+		{PollInterval: -time.Second},
+	}
+	for _, opts := range invalid {
+		_, err := NewQueue(nil, "jobs", opts)
+		Expect(err).To(HaveOccurred())
+	}
+
+	queue, err := NewQueue(nil, "jobs", QueueOptions{VisibilityTimeout: time.Millisecond})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(queue.opts.MaxAttempts).To(Equal(defaultMaxAttempts))
+	Expect(queue.opts.BlockTimeout).To(Equal(defaultBlockTimeout))
+}
+
+func TestQueueRunReportsErrors(t *testing.T) {
+	RegisterTestingT(t)
+
+	// every reply of the server is +OK, so no job can be dequeued
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	queue, err := NewQueue(client, "jobs", QueueOptions{
+		PollInterval: 10 * time.Millisecond,
+		OnError: func(job *Job, err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+			cancel()
+		},
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	handler := func(ctx context.Context, job *Job) error {
+		return nil
+	}
+	Expect(queue.Run(ctx, "test", 1, handler)).To(Equal(context.Canceled))
+	Expect(<-errs).To(HaveOccurred())
+}
@@ -0,0 +1,39 @@
+package ratelimit
+
+import "github.com/valery-barysok/gredis"
+
+// FixedWindow counts events in consecutive windows of fixed length. It is cheap, but allows bursts of up to
+// twice the limit around window boundaries.
+type FixedWindow struct {
+	client *gredis.Client
+	opts   Options
+}
+
+// NewFixedWindow returns fixed window limiter using client, error is returned for invalid options
+func NewFixedWindow(client *gredis.Client, opts Options) (*FixedWindow, error) {
+	if err := opts.init(); err != nil {
+		return nil, err
+	}
+
+	return &FixedWindow{client: client, opts: opts}, nil
+}
+
+// Allow counts event identified by key and decides whether it fits into the limit
+func (l *FixedWindow) Allow(key string) (*Result, error) {
+	window, elapsed := l.opts.window(l.opts.Now())
+
+	count, err := incr(l.client, l.opts.counterKey(key, window), l.opts.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{
+		Allowed:   count <= l.opts.Limit,
+		Remaining: remaining(l.opts.Limit, count),
+	}
+	if !res.Allowed {
+		res.RetryAfter = l.opts.Window - elapsed
+	}
+
+	return res, nil
+}
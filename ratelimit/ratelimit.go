@@ -0,0 +1,97 @@
+// Package ratelimit limits rate of events shared by many processes using counters stored in GRedis server.
+//
+// Counters are changed only with INCR, which is atomic on server side, so concurrent callers never lose
+// updates. Expiry of counters is set in the same round trip and only serves as cleanup, because every
+// window has its own key. Rejected attempts are counted too, so callers exceeding the limit keep being
+// rejected until the window moves on.
+//
+// Token bucket is not provided: it needs read-modify-write of several values, which GRedis server can not
+// do atomically without transactions or scripting.
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/valery-barysok/gredis"
+)
+
+const defaultPrefix = "ratelimit:"
+
+// Options provides settings for limiters
+type Options struct {
+	// Limit is the number of events allowed per Window, it must be positive
+	Limit int
+	// Window is the length of time window, it must be at least a millisecond, counters expire with
+	// millisecond precision
+	Window time.Duration
+	// Prefix is prepended to keys of counters, "ratelimit:" by default
+	Prefix string
+	// Now returns current time, time.Now by default. Set it to control time in tests.
+	Now func() time.Time
+}
+
+// Result is the decision of limiter
+type Result struct {
+	// Allowed is true if the event fits into the limit
+	Allowed bool
+	// Remaining is the number of events still allowed in current window
+	Remaining int
+	// RetryAfter is the time after which the event may be allowed, it is zero for allowed events
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether event identified by key is allowed
+type Limiter interface {
+	Allow(key string) (*Result, error)
+}
+
+// init validates options and sets defaults
+func (opts *Options) init() error {
+	if opts.Limit <= 0 {
+		return fmt.Errorf("invalid limit: %d", opts.Limit)
+	}
+	if opts.Window < time.Millisecond {
+		return fmt.Errorf("invalid window: %s", opts.Window)
+	}
+
+	if opts.Prefix == "" {
+		opts.Prefix = defaultPrefix
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	return nil
+}
+
+// window returns index of window containing now and time elapsed since its start
+func (opts *Options) window(now time.Time) (int64, time.Duration) {
+	ns := now.UnixNano()
+	return ns / int64(opts.Window), time.Duration(ns % int64(opts.Window))
+}
+
+func (opts *Options) counterKey(key string, window int64) string {
+	return opts.Prefix + key + ":" + strconv.FormatInt(window, 10)
+}
+
+// incr increments counter and expires it after ttl in a single round trip
+func incr(client *gredis.Client, counter string, ttl time.Duration) (int, error) {
+	incr := gredis.NewCmd(gredis.IncrCommand, []byte(counter))
+	expire := gredis.NewCmd(gredis.PExpireCommand, []byte(counter),
+		[]byte(strconv.FormatInt(int64(ttl/time.Millisecond), 10)))
+	if err := client.Pipeline(incr, expire); err != nil {
+		return 0, err
+	}
+
+	return incr.Reply.Int(), nil
+}
+
+func remaining(limit int, count int) int {
+	if count >= limit {
+		return 0
+	}
+
+	return limit - count
+}
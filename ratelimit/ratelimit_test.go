@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredis"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func dial() (*gredis.Client, error) {
+	opts, err := gredis.NewOptions("gredis://localhost")
+	if err != nil {
+		return nil, err
+	}
+
+	return gredis.Dial(opts)
+}
+
+func TestFixedWindow(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	client, err := dial()
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	c := &clock{now: time.Unix(6000, 0).Add(10 * time.Second)}
+	limiter, err := NewFixedWindow(client, Options{Limit: 3, Window: time.Minute, Now: c.Now})
+	Expect(err).ToNot(HaveOccurred())
+
+	for i := 2; i >= 0; i-- {
+		res, err := limiter.Allow("api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res).To(Equal(&Result{Allowed: true, Remaining: i}))
+	}
+
+	res, err := limiter.Allow("api")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(res).To(Equal(&Result{Allowed: false, Remaining: 0, RetryAfter: 50 * time.Second}))
+
+	// other keys are limited separately
+	res, err = limiter.Allow("other")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(res.Allowed).To(BeTrue())
+
+	c.now = c.now.Add(50 * time.Second)
+	res, err = limiter.Allow("api")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(res).To(Equal(&Result{Allowed: true, Remaining: 2}))
+}
+
+func TestSlidingWindow(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	client, err := dial()
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	c := &clock{now: time.Unix(6000, 0)}
+	limiter, err := NewSlidingWindow(client, Options{Limit: 10, Window: time.Minute, Now: c.Now})
+	Expect(err).ToNot(HaveOccurred())
+
+	for i := 0; i < 10; i++ {
+		res, err := limiter.Allow("api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Allowed).To(BeTrue())
+	}
+
+	// 11 events of current window decay below the limit 2/11 of the next window later
+	res, err := limiter.Allow("api")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(res.Allowed).To(BeFalse())
+	Expect(res.RetryAfter).To(BeNumerically("~", time.Minute+10909090909, time.Microsecond))
+
+	// half of the previous window is still covered: ceil(11 * 0.5) + 1 = 7
+	c.now = c.now.Add(90 * time.Second)
+	res, err = limiter.Allow("api")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(res).To(Equal(&Result{Allowed: true, Remaining: 3}))
+
+	for i := 0; i < 3; i++ {
+		res, err = limiter.Allow("api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Allowed).To(BeTrue())
+	}
+
+	res, err = limiter.Allow("api")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(res.Allowed).To(BeFalse())
+	Expect(res.RetryAfter).To(BeNumerically(">", 0))
+	Expect(res.RetryAfter).To(BeNumerically("<", 30*time.Second))
+
+	c.now = c.now.Add(res.RetryAfter)
+	res, err = limiter.Allow("api")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(res.Allowed).To(BeTrue())
+
+	// the previous window is not covered anymore
+	c.now = c.now.Add(90 * time.Second)
+	res, err = limiter.Allow("api")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(res).To(Equal(&Result{Allowed: true, Remaining: 9}))
+}
+
+func TestInvalidOptions(t *testing.T) {
+	RegisterTestingT(t)
+
+	invalid := []Options{
+		{Limit: 0, Window: time.Minute},
+		{Limit: -1, Window: time.Minute},
+		{Limit: 1, Window: 0},
+		{Limit: 1, Window: time.Microsecond},
+	}
+	for _, opts := range invalid {
+		_, err := NewFixedWindow(nil, opts)
+		Expect(err).To(HaveOccurred())
+		_, err = NewSlidingWindow(nil, opts)
+		Expect(err).To(HaveOccurred())
+	}
+}
+
+func TestSlidingWindowRetryAfter(t *testing.T) {
+	RegisterTestingT(t)
+
+	l, err := NewSlidingWindow(nil, Options{Limit: 10, Window: time.Minute})
+	Expect(err).ToNot(HaveOccurred())
+
+	// weighted count including the next event at t since start of current window
+	count := func(previous int, current int, t time.Duration) int {
+		if t >= l.opts.Window {
+			previous, current, t = current, 0, t-l.opts.Window
+		}
+		weight := 1 - float64(t)/float64(l.opts.Window)
+		return int(math.Ceil(float64(previous)*weight)) + current + 1
+	}
+
+	cases := []struct {
+		previous int
+		current  int
+		elapsed  time.Duration
+	}{
+		{0, 11, 0},
+		{0, 30, 10 * time.Second},
+		{11, 5, 30 * time.Second},
+		{20, 9, 10 * time.Second},
+		{20, 10, 50 * time.Second},
+		{100, 12, 59 * time.Second},
+	}
+	for _, c := range cases {
+		retry := l.retryAfter(c.previous, c.current, c.elapsed)
+		Expect(count(c.previous, c.current, c.elapsed+retry)).To(BeNumerically("<=", l.opts.Limit))
+		Expect(count(c.previous, c.current, c.elapsed+retry-time.Millisecond)).To(BeNumerically(">", l.opts.Limit))
+	}
+}
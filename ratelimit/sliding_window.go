@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/valery-barysok/gredis"
+)
+
+// SlidingWindow approximates number of events in the last Window by weighting count of previous fixed
+// window with the part of it still covered by the sliding one. It smooths out bursts at window boundaries
+// allowed by FixedWindow at the cost of one more key read.
+type SlidingWindow struct {
+	client *gredis.Client
+	opts   Options
+}
+
+// NewSlidingWindow returns sliding window limiter using client, error is returned for invalid options
+func NewSlidingWindow(client *gredis.Client, opts Options) (*SlidingWindow, error) {
+	if err := opts.init(); err != nil {
+		return nil, err
+	}
+
+	return &SlidingWindow{client: client, opts: opts}, nil
+}
+
+// Allow counts event identified by key and decides whether it fits into the limit
+func (l *SlidingWindow) Allow(key string) (*Result, error) {
+	window, elapsed := l.opts.window(l.opts.Now())
+
+	// previous window must outlive the whole current one to be weighted
+	current, err := incr(l.client, l.opts.counterKey(key, window), 2*l.opts.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := 0
+	data, err := l.client.Get(l.opts.counterKey(key, window-1))
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		if previous, err = strconv.Atoi(string(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	count := l.weighted(previous, elapsed) + current
+
+	res := &Result{
+		Allowed:   count <= l.opts.Limit,
+		Remaining: remaining(l.opts.Limit, count),
+	}
+	if !res.Allowed {
+		res.RetryAfter = l.retryAfter(previous, current, elapsed)
+	}
+
+	return res, nil
+}
+
+// retryAfter returns time until weighted count drops below the limit, so the next event is allowed,
+// assuming no more events are counted. The count first decays within current window and then, when current
+// window becomes the previous one, within the next window.
+func (l *SlidingWindow) retryAfter(previous int, current int, elapsed time.Duration) time.Duration {
+	// weighted(previous, t) + current + 1 <= Limit
+	if room := l.opts.Limit - current - 1; room >= 0 {
+		if t := l.decay(previous, room); t < l.opts.Window {
+			if t <= elapsed {
+				return 0
+			}
+			return t - elapsed
+		}
+	}
+
+	// weighted(current, t) + 1 <= Limit in the next window
+	return l.opts.Window - elapsed + l.decay(current, l.opts.Limit-1)
+}
+
+// weighted returns count of previous window weighted with the part of it covered by sliding window
+func (l *SlidingWindow) weighted(previous int, elapsed time.Duration) int {
+	weight := 1 - float64(elapsed)/float64(l.opts.Window)
+	return int(math.Ceil(float64(previous) * weight))
+}
+
+// decay returns the earliest time since start of window when weighted count of previous events is at most
+// room, room must not be negative
+func (l *SlidingWindow) decay(previous int, room int) time.Duration {
+	if previous <= room {
+		return 0
+	}
+
+	window := float64(l.opts.Window)
+	t := time.Duration(window * (1 - float64(room)/float64(previous)))
+	// step over rounding errors of the estimate
+	for t < l.opts.Window && l.weighted(previous, t) > room {
+		t++
+	}
+
+	return t
+}
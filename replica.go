@@ -0,0 +1,465 @@
+package gredis
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valery-barysok/resp"
+)
+
+const defaultHealthCheckInterval = time.Second
+
+// ReadPolicy defines how ReplicaSet picks replica for read commands
+type ReadPolicy int
+
+// List of read policies
+const (
+	ReadRoundRobin ReadPolicy = iota
+	ReadLowestLatency
+	ReadRandom
+)
+
+var readCommands = map[string]bool{
+	string(EchoCommand):    true,
+	string(PingCommand):    true,
+	string(KeysCommand):    true,
+	string(ExistsCommand):  true,
+	string(TTLCommand):     true,
+	string(TypeCommand):    true,
+	string(GetCommand):     true,
+	string(LLenCommand):    true,
+	string(LIndexCommand):  true,
+	string(LRangeCommand):  true,
+	string(HGetCommand):    true,
+	string(HLenCommand):    true,
+	string(HExistsCommand): true,
+	string(HKeysCommand):   true,
+}
+
+// IsReadCommand returns true if cmd does not modify data, so it can be sent to replica
+func IsReadCommand(cmd []byte) bool {
+	return readCommands[string(cmd)]
+}
+
+// ReplicaSetOptions provides settings for ReplicaSet
+type ReplicaSetOptions struct {
+	ReadPolicy ReadPolicy
+	// HealthCheckInterval is the interval between pings of replicas, 1 second by default
+	HealthCheckInterval time.Duration
+}
+
+type replica struct {
+	opts    *Options
+	client  *Client
+	healthy bool
+	latency time.Duration
+}
+
+// ReplicaSet sends write commands to primary and spreads read commands across healthy replicas according to
+// read policy. Replicas are pinged periodically, a replica failing to reply is ejected until it replies
+// again. Reads fall back to primary when there are no healthy replicas.
+type ReplicaSet struct {
+	primary *Client
+	policy  ReadPolicy
+
+	mu       sync.Mutex
+	replicas []*replica
+	next     uint64
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewReplicaSet connects to primary and replicas with specified URLs, see NewOptions for supported formats
+func NewReplicaSet(primaryURL string, replicaURLs []string, opts ReplicaSetOptions) (*ReplicaSet, error) {
+	primaryOpts, err := NewOptions(primaryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	primary, err := Dial(primaryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &ReplicaSet{
+		primary: primary,
+		policy:  opts.ReadPolicy,
+		done:    make(chan struct{}),
+	}
+
+	for _, rawURL := range replicaURLs {
+		replicaOpts, err := NewOptions(rawURL)
+		if err != nil {
+			rs.closeClients()
+			return nil, err
+		}
+
+		// unreachable replica is not fatal, health checker connects to it later
+		client, _ := Dial(replicaOpts)
+		rs.replicas = append(rs.replicas, &replica{
+			opts:    replicaOpts,
+			client:  client,
+			healthy: client != nil,
+		})
+	}
+
+	interval := opts.HealthCheckInterval
+	if interval == 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	rs.wg.Add(1)
+	go rs.healthCheck(interval)
+
+	return rs, nil
+}
+
+// Close stops health checker and closes all connections, subsequent calls do nothing
+func (rs *ReplicaSet) Close() {
+	rs.closeOnce.Do(func() {
+		close(rs.done)
+		rs.wg.Wait()
+		rs.closeClients()
+	})
+}
+
+func (rs *ReplicaSet) closeClients() {
+	rs.primary.Close()
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for _, r := range rs.replicas {
+		if r.client != nil {
+			r.client.Close()
+		}
+	}
+}
+
+// Primary returns client connected to primary
+func (rs *ReplicaSet) Primary() *Client {
+	return rs.primary
+}
+
+// HealthyReplicas returns the number of replicas currently used for reads
+func (rs *ReplicaSet) HealthyReplicas() int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	cnt := 0
+	for _, r := range rs.replicas {
+		if r.healthy {
+			cnt++
+		}
+	}
+
+	return cnt
+}
+
+func (rs *ReplicaSet) healthCheck(interval time.Duration) {
+	defer rs.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rs.done:
+			return
+		case <-ticker.C:
+		}
+
+		rs.mu.Lock()
+		replicas := append([]*replica(nil), rs.replicas...)
+		rs.mu.Unlock()
+
+		for _, r := range replicas {
+			rs.check(r)
+		}
+	}
+}
+
+// check pings replica, reconnecting to it first when it has no connection
+func (rs *ReplicaSet) check(r *replica) {
+	rs.mu.Lock()
+	client := r.client
+	rs.mu.Unlock()
+
+	if client == nil {
+		var err error
+		if client, err = Dial(r.opts); err != nil {
+			return
+		}
+
+		rs.mu.Lock()
+		r.client = client
+		rs.mu.Unlock()
+	}
+
+	start := time.Now()
+	_, err := client.Ping()
+	latency := time.Since(start)
+
+	if err != nil {
+		rs.eject(r, client)
+		return
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r.healthy = true
+	if r.latency == 0 {
+		r.latency = latency
+	} else {
+		// exponentially weighted moving average smooths out spikes
+		r.latency = (r.latency*7 + latency) / 8
+	}
+}
+
+// eject marks replica unhealthy and drops its broken connection
+func (rs *ReplicaSet) eject(r *replica, client *Client) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r.healthy = false
+	if r.client == client {
+		r.client = nil
+		client.Close()
+	}
+}
+
+func (rs *ReplicaSet) pick() (*replica, *Client) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	healthy := make([]*replica, 0, len(rs.replicas))
+	for _, r := range rs.replicas {
+		if r.healthy && r.client != nil {
+			healthy = append(healthy, r)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return nil, nil
+	}
+
+	var r *replica
+	switch rs.policy {
+	case ReadLowestLatency:
+		r = healthy[0]
+		for _, candidate := range healthy[1:] {
+			if candidate.latency < r.latency {
+				r = candidate
+			}
+		}
+	case ReadRandom:
+		r = healthy[rand.Intn(len(healthy))]
+	default:
+		r = healthy[atomic.AddUint64(&rs.next, 1)%uint64(len(healthy))]
+	}
+
+	return r, r.client
+}
+
+// read runs fn on replica picked by read policy. When replica drops the connection or its client was closed
+// by eject of a concurrent call, it is ejected and fn is retried on primary. Timeouts are returned as is:
+// a slow command would likely time out on primary as well.
+func (rs *ReplicaSet) read(fn func(client *Client) error) error {
+	r, client := rs.pick()
+	if r == nil {
+		return fn(rs.primary)
+	}
+
+	err := fn(client)
+	if err != nil && (isConnDropped(err) || err == ErrClosed) {
+		rs.eject(r, client)
+		return fn(rs.primary)
+	}
+
+	return err
+}
+
+// Do sends read commands to replica and all other commands to primary
+func (rs *ReplicaSet) Do(cmd []byte, args ...[]byte) (msg *resp.Message, err error) {
+	if !IsReadCommand(cmd) {
+		return rs.primary.Do(cmd, args...)
+	}
+
+	err = rs.read(func(client *Client) error {
+		msg, err = client.Do(cmd, args...)
+		return err
+	})
+	return msg, err
+}
+
+// Ping pings replica picked by read policy, see Client.Ping.
+func (rs *ReplicaSet) Ping() (res string, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.Ping()
+		return err
+	})
+	return res, err
+}
+
+// Keys returns Bulk Array of all keys matching **regexp** pattern, see Client.Keys.
+func (rs *ReplicaSet) Keys(pattern string) (res [][]byte, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.Keys(pattern)
+		return err
+	})
+	return res, err
+}
+
+// Exists returns if keys exist with count of such keys, see Client.Exists.
+func (rs *ReplicaSet) Exists(key string, keys ...string) (res int, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.Exists(key, keys...)
+		return err
+	})
+	return res, err
+}
+
+// TTL returns the remaining time to live of a key, see Client.TTL.
+func (rs *ReplicaSet) TTL(key string) (res int, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.TTL(key)
+		return err
+	})
+	return res, err
+}
+
+// Type returns the type of the value stored at key, see Client.Type.
+func (rs *ReplicaSet) Type(key string) (res string, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.Type(key)
+		return err
+	})
+	return res, err
+}
+
+// Get the value of key, see Client.Get.
+func (rs *ReplicaSet) Get(key string) (res []byte, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.Get(key)
+		return err
+	})
+	return res, err
+}
+
+// LLen returns the length of the list stored at key, see Client.LLen.
+func (rs *ReplicaSet) LLen(key string) (res int, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.LLen(key)
+		return err
+	})
+	return res, err
+}
+
+// LIndex returns the element at index index in the list stored at key, see Client.LIndex.
+func (rs *ReplicaSet) LIndex(key string, index int) (res []byte, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.LIndex(key, index)
+		return err
+	})
+	return res, err
+}
+
+// LRange returns the specified elements of the list stored at key, see Client.LRange.
+func (rs *ReplicaSet) LRange(key string, start int, stop int) (res [][]byte, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.LRange(key, start, stop)
+		return err
+	})
+	return res, err
+}
+
+// HGet returns the value associated with field in the hash stored at key.
+func (rs *ReplicaSet) HGet(key string, field string) (res []byte, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.HGet(key, field)
+		return err
+	})
+	return res, err
+}
+
+// HLen returns the number of fields contained in the hash stored at key or 0 when key does not exist.
+func (rs *ReplicaSet) HLen(key string) (res int, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.HLen(key)
+		return err
+	})
+	return res, err
+}
+
+// HExists returns if field is an existing field in the hash stored at key, see Client.HExists.
+func (rs *ReplicaSet) HExists(key string, field string) (res int, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.HExists(key, field)
+		return err
+	})
+	return res, err
+}
+
+// HKeys returns all field names in the hash stored at key or empty list when key does not exist.
+func (rs *ReplicaSet) HKeys(key string) (res [][]byte, err error) {
+	err = rs.read(func(client *Client) error {
+		res, err = client.HKeys(key)
+		return err
+	})
+	return res, err
+}
+
+// Set key to hold the string value on primary, see Client.Set.
+func (rs *ReplicaSet) Set(key string, value string) (bool, error) {
+	return rs.primary.Set(key, value)
+}
+
+// Del removes the specified keys on primary, see Client.Del.
+func (rs *ReplicaSet) Del(key string, keys ...string) (int, error) {
+	return rs.primary.Del(key, keys...)
+}
+
+// Expire sets a timeout on key on primary, see Client.Expire.
+func (rs *ReplicaSet) Expire(key string, seconds int) (int, error) {
+	return rs.primary.Expire(key, seconds)
+}
+
+// LPush inserts values at the head of the list stored at key on primary, see Client.LPush.
+func (rs *ReplicaSet) LPush(key string, value string, values ...string) (int, error) {
+	return rs.primary.LPush(key, value, values...)
+}
+
+// RPush inserts values at the tail of the list stored at key on primary, see Client.RPush.
+func (rs *ReplicaSet) RPush(key string, value string, values ...string) (int, error) {
+	return rs.primary.RPush(key, value, values...)
+}
+
+// LPop removes and returns the first element of the list stored at key on primary.
+func (rs *ReplicaSet) LPop(key string) ([]byte, error) {
+	return rs.primary.LPop(key)
+}
+
+// RPop removes and returns the last element of the list stored at key on primary.
+func (rs *ReplicaSet) RPop(key string) ([]byte, error) {
+	return rs.primary.RPop(key)
+}
+
+// LInsert inserts value in the list stored at key on primary, see Client.LInsert.
+func (rs *ReplicaSet) LInsert(key string, before bool, pivot string, value string) (int, error) {
+	return rs.primary.LInsert(key, before, pivot, value)
+}
+
+// HSet sets field in the hash stored at key to value on primary, see Client.HSet.
+func (rs *ReplicaSet) HSet(key string, field string, value string) (int, error) {
+	return rs.primary.HSet(key, field, value)
+}
+
+// HDel removes the specified fields from the hash stored at key on primary, see Client.HDel.
+func (rs *ReplicaSet) HDel(key string, field string, fields ...string) (int, error) {
+	return rs.primary.HDel(key, field, fields...)
+}
@@ -0,0 +1,178 @@
+package gredis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+func TestIsReadCommand(t *testing.T) {
+	RegisterTestingT(t)
+
+	for _, cmd := range [][]byte{GetCommand, LRangeCommand, HGetCommand, ExistsCommand} {
+		Expect(IsReadCommand(cmd)).To(BeTrue(), string(cmd))
+	}
+
+	for _, cmd := range [][]byte{SetCommand, LPushCommand, HDelCommand, ExpireCommand} {
+		Expect(IsReadCommand(cmd)).To(BeFalse(), string(cmd))
+	}
+}
+
+func TestReplicaSetPick(t *testing.T) {
+	RegisterTestingT(t)
+
+	fast := &replica{client: &Client{}, healthy: true, latency: time.Millisecond}
+	slow := &replica{client: &Client{}, healthy: true, latency: 10 * time.Millisecond}
+	down := &replica{client: &Client{}, healthy: false}
+
+	rs := &ReplicaSet{replicas: []*replica{slow, down, fast}}
+
+	rs.policy = ReadLowestLatency
+	r, _ := rs.pick()
+	Expect(r).To(BeIdenticalTo(fast))
+
+	rs.policy = ReadRoundRobin
+	seen := make(map[*replica]int)
+	for i := 0; i < 10; i++ {
+		r, _ := rs.pick()
+		seen[r]++
+	}
+	Expect(seen[fast]).To(Equal(5))
+	Expect(seen[slow]).To(Equal(5))
+	Expect(seen[down]).To(Equal(0))
+
+	rs.policy = ReadRandom
+	for i := 0; i < 10; i++ {
+		r, _ := rs.pick()
+		Expect(r).ToNot(BeIdenticalTo(down))
+	}
+
+	fast.healthy = false
+	slow.healthy = false
+	r, client := rs.pick()
+	Expect(r).To(BeNil())
+	Expect(client).To(BeNil())
+}
+
+func TestReplicaSetReadEjected(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	primary, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer primary.Close()
+
+	// client of replica closed by eject of a concurrent read
+	ejected, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(ejected.Close()).To(Succeed())
+
+	r := &replica{client: ejected, healthy: true}
+	rs := &ReplicaSet{primary: primary, replicas: []*replica{r}}
+
+	var used *Client
+	err = rs.read(func(client *Client) error {
+		used = client
+		_, err := client.Ping()
+		return err
+	})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(used).To(BeIdenticalTo(primary))
+	Expect(r.healthy).To(BeFalse())
+}
+
+func TestReplicaSet(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	rs, err := NewReplicaSet("gredis://localhost/1", []string{
+		"gredis://localhost/1",
+		"gredis://localhost:1/1",
+	}, ReplicaSetOptions{
+		ReadPolicy:          ReadLowestLatency,
+		HealthCheckInterval: 10 * time.Millisecond,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	defer rs.Close()
+
+	Expect(rs.HealthyReplicas()).To(Equal(1))
+
+	_, err = rs.Set("key", "value")
+	Expect(err).ToNot(HaveOccurred())
+
+	value, err := rs.Get("key")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(value).To(BeEquivalentTo("value"))
+
+	msg, err := rs.Do(GetCommand, []byte("key"))
+	Expect(err).ToNot(HaveOccurred())
+	Expect(msg.BulkString()).To(BeEquivalentTo("value"))
+
+	time.Sleep(50 * time.Millisecond)
+	Expect(rs.HealthyReplicas()).To(Equal(1))
+
+	cnt, err := rs.Del("key")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(cnt).To(Equal(1))
+}
+
+func TestReplicaSetReadTimeout(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(map[string]time.Duration{"PING": 200 * time.Millisecond})
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost?read_timeout=50ms")
+	Expect(err).ToNot(HaveOccurred())
+
+	primary, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer primary.Close()
+
+	slow, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer slow.Close()
+
+	r := &replica{client: slow, healthy: true}
+	rs := &ReplicaSet{primary: primary, replicas: []*replica{r}}
+
+	calls := 0
+	err = rs.read(func(client *Client) error {
+		calls++
+		_, err := client.Ping()
+		return err
+	})
+	Expect(errors.Is(err, ErrTimeout)).To(BeTrue())
+	Expect(calls).To(Equal(1))
+	Expect(r.healthy).To(BeTrue())
+}
+
+func TestReplicaSetCloseTwice(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	url := "gredis://" + s.l.Addr().String()
+	rs, err := NewReplicaSet(url, []string{url}, ReplicaSetOptions{})
+	Expect(err).ToNot(HaveOccurred())
+
+	rs.Close()
+	// second call must not panic on closed channel
+	rs.Close()
+}
@@ -0,0 +1,807 @@
+package gredis
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultVirtualNodes = 160
+
+var errNoShards = errors.New("ring has no shards")
+
+// hashRing maps keys to shard names by consistent hashing with virtual nodes
+type hashRing struct {
+	vnodes int
+	hashes []uint32
+	owners map[uint32]string
+}
+
+func newHashRing(vnodes int) *hashRing {
+	return &hashRing{
+		vnodes: vnodes,
+		owners: make(map[uint32]string),
+	}
+}
+
+// add places vnodes points of shard name on the ring. A point colliding with a point of another shard is
+// rehashed, so shards never take over points of each other.
+func (r *hashRing) add(name string) {
+	for i := 0; i < r.vnodes; i++ {
+		if h, ok := r.point(name, i); ok {
+			r.hashes = append(r.hashes, h)
+			r.owners[h] = name
+		}
+	}
+
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+}
+
+// point returns hash of i-th virtual node of shard name not taken by another shard, false is returned when
+// the shard already owns it
+func (r *hashRing) point(name string, i int) (uint32, bool) {
+	vnode := name + "-" + strconv.Itoa(i)
+	h := crc32.ChecksumIEEE([]byte(vnode))
+
+	for attempt := 1; ; attempt++ {
+		owner, ok := r.owners[h]
+		if !ok {
+			return h, true
+		}
+		if owner == name {
+			return 0, false
+		}
+		h = crc32.ChecksumIEEE([]byte(vnode + "-" + strconv.Itoa(attempt)))
+	}
+}
+
+// remove deletes only points owned by shard name
+func (r *hashRing) remove(name string) {
+	hashes := r.hashes[:0]
+	for _, h := range r.hashes {
+		if r.owners[h] == name {
+			delete(r.owners, h)
+			continue
+		}
+		hashes = append(hashes, h)
+	}
+
+	r.hashes = hashes
+}
+
+func (r *hashRing) get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	h := crc32.ChecksumIEEE([]byte(hashTag(key)))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+
+	return r.owners[r.hashes[i]]
+}
+
+// hashTag returns the part of key between the first `{` and the next `}` when it is not empty, otherwise
+// the whole key. Keys with the same hash tag are always stored on the same shard.
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+
+	return key[start+1 : start+1+end]
+}
+
+// Ring is a client sharding keys across multiple GRedis servers by consistent hashing. Commands, AddShard
+// and RemoveShard can be called concurrently. A command racing with RemoveShard of its shard may fail with
+// ErrClosed.
+type Ring struct {
+	mu     sync.RWMutex
+	ring   *hashRing
+	shards map[string]*Client
+}
+
+// ringCommands lists commands Ring shares with Client. Commands not listed are deliberately left out: they
+// work on a connection rather than on keys (Do, DoArgs, DoContext, Process, Pipeline, Send, Flush, Receive,
+// AddHook, Auth, Select, Echo, PingMsg, Command, Shutdown, ShutdownSave, ShutdownNoSave, Addr, Stats,
+// StatsHandler, PublishExpvar) or differ in signature (Close). Use ShardForKey to reach them.
+type ringCommands interface {
+	Ping() (string, error)
+	Keys(pattern string) ([][]byte, error)
+	KeysEach(pattern string, fn func(key []byte) error) error
+	Exists(key string, keys ...string) (int, error)
+	Del(key string, keys ...string) (int, error)
+	Expire(key string, seconds int) (int, error)
+	PExpire(key string, milliseconds int) (int, error)
+	TTL(key string) (int, error)
+	Type(key string) (string, error)
+	Set(key string, value string) (bool, error)
+	SetBytes(key string, value []byte) (bool, error)
+	SetValue(key string, v interface{}) (bool, error)
+	Get(key string) ([]byte, error)
+	GetValue(key string, v interface{}) error
+	Incr(key string) (int, error)
+	LPush(key string, value string, values ...string) (int, error)
+	LPushBytes(key string, value []byte, values ...[]byte) (int, error)
+	LPushValue(key string, v interface{}, values ...interface{}) (int, error)
+	RPush(key string, value string, values ...string) (int, error)
+	RPushBytes(key string, value []byte, values ...[]byte) (int, error)
+	LPop(key string) ([]byte, error)
+	RPop(key string) ([]byte, error)
+	LLen(key string) (int, error)
+	LInsert(key string, before bool, pivot string, value string) (int, error)
+	LInsertBytes(key string, before bool, pivot []byte, value []byte) (int, error)
+	LIndex(key string, index int) ([]byte, error)
+	LRange(key string, start int, stop int) ([][]byte, error)
+	LRangeIter(key string, start int, stop int, pageSize int) *ListIterator
+	LRangeEach(key string, start int, stop int, pageSize int, fn func(value []byte) error) error
+	LRangeValues(key string, start int, stop int, values interface{}) error
+	LRem(key string, count int, value string) (int, error)
+	RPopLPush(source string, destination string) ([]byte, error)
+	BRPopLPush(source string, destination string, timeout int) ([]byte, error)
+	HSet(key string, field string, value string) (int, error)
+	HSetBytes(key string, field string, value []byte) (int, error)
+	HSetValue(key string, field string, v interface{}) (int, error)
+	HSetStruct(key string, v interface{}) (int, error)
+	HGet(key string, field string) ([]byte, error)
+	HGetValue(key string, field string, v interface{}) error
+	HGetStruct(key string, v interface{}) error
+	HDel(key string, field string, fields ...string) (int, error)
+	HLen(key string) (int, error)
+	HExists(key string, field string) (int, error)
+	HKeys(key string) ([][]byte, error)
+}
+
+var (
+	_ ringCommands = (*Client)(nil)
+	_ ringCommands = (*Ring)(nil)
+)
+
+var errCrossShard = errors.New("keys belong to different shards, use hash tags to keep them together")
+
+// NewRing connects to every server from rawURLs, see NewOptions for supported formats, and returns Ring
+// routing keys across them
+func NewRing(rawURLs ...string) (*Ring, error) {
+	ring := &Ring{
+		ring:   newHashRing(defaultVirtualNodes),
+		shards: make(map[string]*Client),
+	}
+
+	for _, rawURL := range rawURLs {
+		if err := ring.AddShard(rawURL); err != nil {
+			ring.Close()
+			return nil, err
+		}
+	}
+
+	return ring, nil
+}
+
+// AddShard connects to server with rawURL and adds it to the ring. Only keys falling to the new shard's
+// virtual nodes change their shard.
+func (ring *Ring) AddShard(rawURL string) error {
+	if ring.hasShard(rawURL) {
+		return fmt.Errorf("shard %s already exists", rawURL)
+	}
+
+	opts, err := NewOptions(rawURL)
+	if err != nil {
+		return err
+	}
+
+	// commands keep going to other shards while connecting
+	client, err := Dial(opts)
+	if err != nil {
+		return err
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	if _, ok := ring.shards[rawURL]; ok {
+		client.Close()
+		return fmt.Errorf("shard %s already exists", rawURL)
+	}
+
+	ring.shards[rawURL] = client
+	ring.ring.add(rawURL)
+
+	return nil
+}
+
+func (ring *Ring) hasShard(rawURL string) bool {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	_, ok := ring.shards[rawURL]
+	return ok
+}
+
+// RemoveShard removes shard with rawURL from the ring and closes connection to it. Only keys of the removed
+// shard change their shard.
+func (ring *Ring) RemoveShard(rawURL string) error {
+	ring.mu.Lock()
+	client, ok := ring.shards[rawURL]
+	if ok {
+		ring.ring.remove(rawURL)
+		delete(ring.shards, rawURL)
+	}
+	ring.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("shard %s does not exist", rawURL)
+	}
+
+	client.Close()
+
+	return nil
+}
+
+// Shards returns URLs of all shards
+func (ring *Ring) Shards() []string {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	res := make([]string, 0, len(ring.shards))
+	for rawURL := range ring.shards {
+		res = append(res, rawURL)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// ShardForKey returns client of the shard owning key
+func (ring *Ring) ShardForKey(key string) (*Client, error) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	return ring.shardForKey(key)
+}
+
+// shardForKey is ShardForKey called with mu held
+func (ring *Ring) shardForKey(key string) (*Client, error) {
+	client, ok := ring.shards[ring.ring.get(key)]
+	if !ok {
+		return nil, errNoShards
+	}
+
+	return client, nil
+}
+
+// clients returns clients of all shards, so commands are sent to them without holding mu
+func (ring *Ring) clients() []*Client {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	res := make([]*Client, 0, len(ring.shards))
+	for _, client := range ring.shards {
+		res = append(res, client)
+	}
+
+	return res
+}
+
+// Close closes connections to all shards
+func (ring *Ring) Close() {
+	for _, client := range ring.clients() {
+		client.Close()
+	}
+}
+
+// groupKeys splits keys by shards keeping duplicates
+func (ring *Ring) groupKeys(keys []string) (map[*Client][]string, error) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	res := make(map[*Client][]string)
+	for _, key := range keys {
+		client, err := ring.shardForKey(key)
+		if err != nil {
+			return nil, err
+		}
+		res[client] = append(res[client], key)
+	}
+
+	return res, nil
+}
+
+// shardForKeys returns client of the shard owning all keys, errCrossShard is returned when keys belong to
+// different shards
+func (ring *Ring) shardForKeys(key string, other string) (*Client, error) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	client, err := ring.shardForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if otherClient, _ := ring.shardForKey(other); otherClient != client {
+		return nil, errCrossShard
+	}
+
+	return client, nil
+}
+
+// sumByShard calls fn for keys of every shard and returns the sum of results
+func (ring *Ring) sumByShard(keys []string, fn func(client *Client, key string, keys ...string) (int, error)) (int, error) {
+	groups, err := ring.groupKeys(keys)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for client, keys := range groups {
+		cnt, err := fn(client, keys[0], keys[1:]...)
+		if err != nil {
+			return total, err
+		}
+		total += cnt
+	}
+
+	return total, nil
+}
+
+// Ping pings all shards.
+//
+// Returns `PONG` if success, otherwise empty string.
+func (ring *Ring) Ping() (string, error) {
+	clients := ring.clients()
+	if len(clients) == 0 {
+		return "", errNoShards
+	}
+
+	res := ""
+	for _, client := range clients {
+		pong, err := client.Ping()
+		if err != nil {
+			return "", err
+		}
+		res = pong
+	}
+
+	return res, nil
+}
+
+// Keys returns Bulk Array of all keys matching **regexp** pattern from all shards.
+func (ring *Ring) Keys(pattern string) ([][]byte, error) {
+	var res [][]byte
+	for _, client := range ring.clients() {
+		keys, err := client.Keys(pattern)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, keys...)
+	}
+
+	return res, nil
+}
+
+// KeysEach calls fn for every key matching pattern on all shards, see Client.KeysEach.
+func (ring *Ring) KeysEach(pattern string, fn func(key []byte) error) error {
+	for _, client := range ring.clients() {
+		if err := client.KeysEach(pattern, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Exists returns if keys exist with count of such keys. Keys are checked on their shards.
+func (ring *Ring) Exists(key string, keys ...string) (int, error) {
+	return ring.sumByShard(append([]string{key}, keys...), (*Client).Exists)
+}
+
+// Del removes the specified keys from their shards. A key is ignored if it does not exist.
+//
+// Returns the number of keys that were removed.
+func (ring *Ring) Del(key string, keys ...string) (int, error) {
+	return ring.sumByShard(append([]string{key}, keys...), (*Client).Del)
+}
+
+// Expire sets a timeout on key, see Client.Expire.
+func (ring *Ring) Expire(key string, seconds int) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.Expire(key, seconds)
+}
+
+// TTL returns the remaining time to live of a key, see Client.TTL.
+func (ring *Ring) TTL(key string) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.TTL(key)
+}
+
+// Type returns the type of the value stored at key, see Client.Type.
+func (ring *Ring) Type(key string) (string, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	return client.Type(key)
+}
+
+// Set key to hold the string value, see Client.Set.
+func (ring *Ring) Set(key string, value string) (bool, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	return client.Set(key, value)
+}
+
+// Get the value of key, see Client.Get.
+func (ring *Ring) Get(key string) ([]byte, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Get(key)
+}
+
+// LPush inserts all the specified values at the head of the list stored at key, see Client.LPush.
+func (ring *Ring) LPush(key string, value string, values ...string) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.LPush(key, value, values...)
+}
+
+// RPush inserts all the specified values at the tail of the list stored at key, see Client.RPush.
+func (ring *Ring) RPush(key string, value string, values ...string) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.RPush(key, value, values...)
+}
+
+// LPop removes and returns the first element of the list stored at key.
+func (ring *Ring) LPop(key string) ([]byte, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.LPop(key)
+}
+
+// RPop removes and returns the last element of the list stored at key.
+func (ring *Ring) RPop(key string) ([]byte, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.RPop(key)
+}
+
+// LLen returns the length of the list stored at key, see Client.LLen.
+func (ring *Ring) LLen(key string) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.LLen(key)
+}
+
+// LInsert inserts value in the list stored at key either before or after the reference value pivot,
+// see Client.LInsert.
+func (ring *Ring) LInsert(key string, before bool, pivot string, value string) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.LInsert(key, before, pivot, value)
+}
+
+// LIndex returns the element at index index in the list stored at key, see Client.LIndex.
+func (ring *Ring) LIndex(key string, index int) ([]byte, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.LIndex(key, index)
+}
+
+// LRange returns the specified elements of the list stored at key, see Client.LRange.
+func (ring *Ring) LRange(key string, start int, stop int) ([][]byte, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.LRange(key, start, stop)
+}
+
+// HSet sets field in the hash stored at key to value, see Client.HSet.
+func (ring *Ring) HSet(key string, field string, value string) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.HSet(key, field, value)
+}
+
+// HGet returns the value associated with field in the hash stored at key.
+func (ring *Ring) HGet(key string, field string) ([]byte, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.HGet(key, field)
+}
+
+// HDel removes the specified fields from the hash stored at key, see Client.HDel.
+func (ring *Ring) HDel(key string, field string, fields ...string) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.HDel(key, field, fields...)
+}
+
+// HLen returns the number of fields contained in the hash stored at key or 0 when key does not exist.
+func (ring *Ring) HLen(key string) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.HLen(key)
+}
+
+// HExists returns if field is an existing field in the hash stored at key, see Client.HExists.
+func (ring *Ring) HExists(key string, field string) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.HExists(key, field)
+}
+
+// HKeys returns all field names in the hash stored at key or empty list when key does not exist.
+func (ring *Ring) HKeys(key string) ([][]byte, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.HKeys(key)
+}
+
+// PExpire sets a timeout on key in milliseconds, see Client.PExpire.
+func (ring *Ring) PExpire(key string, milliseconds int) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.PExpire(key, milliseconds)
+}
+
+// SetBytes sets key to hold binary value, see Client.SetBytes.
+func (ring *Ring) SetBytes(key string, value []byte) (bool, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	return client.SetBytes(key, value)
+}
+
+// SetValue encodes v and sets key to hold it, see Client.SetValue.
+func (ring *Ring) SetValue(key string, v interface{}) (bool, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	return client.SetValue(key, v)
+}
+
+// GetValue decodes the value of key into v, see Client.GetValue.
+func (ring *Ring) GetValue(key string, v interface{}) error {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return err
+	}
+
+	return client.GetValue(key, v)
+}
+
+// Incr increments the number stored at key by one, see Client.Incr.
+func (ring *Ring) Incr(key string) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.Incr(key)
+}
+
+// LPushBytes inserts binary values at the head of the list stored at key, see Client.LPushBytes.
+func (ring *Ring) LPushBytes(key string, value []byte, values ...[]byte) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.LPushBytes(key, value, values...)
+}
+
+// LPushValue encodes values and inserts them at the head of the list stored at key, see Client.LPushValue.
+func (ring *Ring) LPushValue(key string, v interface{}, values ...interface{}) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.LPushValue(key, v, values...)
+}
+
+// RPushBytes inserts binary values at the tail of the list stored at key, see Client.RPushBytes.
+func (ring *Ring) RPushBytes(key string, value []byte, values ...[]byte) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.RPushBytes(key, value, values...)
+}
+
+// LInsertBytes is the binary variant of LInsert, see Client.LInsertBytes.
+func (ring *Ring) LInsertBytes(key string, before bool, pivot []byte, value []byte) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.LInsertBytes(key, before, pivot, value)
+}
+
+// LRangeEach calls fn for every element of the list stored at key, see Client.LRangeEach.
+func (ring *Ring) LRangeEach(key string, start int, stop int, pageSize int, fn func(value []byte) error) error {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return err
+	}
+
+	return client.LRangeEach(key, start, stop, pageSize, fn)
+}
+
+// LRangeValues decodes elements of the list stored at key into values, see Client.LRangeValues.
+func (ring *Ring) LRangeValues(key string, start int, stop int, values interface{}) error {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return err
+	}
+
+	return client.LRangeValues(key, start, stop, values)
+}
+
+// LRem removes count occurrences of value from the list stored at key, see Client.LRem.
+func (ring *Ring) LRem(key string, count int, value string) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.LRem(key, count, value)
+}
+
+// HSetBytes sets field in the hash stored at key to binary value, see Client.HSetBytes.
+func (ring *Ring) HSetBytes(key string, field string, value []byte) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.HSetBytes(key, field, value)
+}
+
+// HSetValue encodes v and sets field in the hash stored at key to it, see Client.HSetValue.
+func (ring *Ring) HSetValue(key string, field string, v interface{}) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.HSetValue(key, field, v)
+}
+
+// HSetStruct stores fields of struct v in the hash stored at key, see Client.HSetStruct.
+func (ring *Ring) HSetStruct(key string, v interface{}) (int, error) {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.HSetStruct(key, v)
+}
+
+// HGetValue decodes field of the hash stored at key into v, see Client.HGetValue.
+func (ring *Ring) HGetValue(key string, field string, v interface{}) error {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return err
+	}
+
+	return client.HGetValue(key, field, v)
+}
+
+// HGetStruct loads the hash stored at key into struct pointed by v, see Client.HGetStruct.
+func (ring *Ring) HGetStruct(key string, v interface{}) error {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return err
+	}
+
+	return client.HGetStruct(key, v)
+}
+
+// LRangeIter returns iterator over the list stored at key, see Client.LRangeIter. Iteration fails if the
+// ring has no shards.
+func (ring *Ring) LRangeIter(key string, start int, stop int, pageSize int) *ListIterator {
+	client, err := ring.ShardForKey(key)
+	if err != nil {
+		return &ListIterator{err: err, done: true}
+	}
+
+	return client.LRangeIter(key, start, stop, pageSize)
+}
+
+// RPopLPush atomically moves the last element of source to the head of destination, see Client.RPopLPush.
+// Both keys must belong to the same shard.
+func (ring *Ring) RPopLPush(source string, destination string) ([]byte, error) {
+	client, err := ring.shardForKeys(source, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.RPopLPush(source, destination)
+}
+
+// BRPopLPush is the blocking variant of RPopLPush, see Client.BRPopLPush. Both keys must belong to the same
+// shard.
+func (ring *Ring) BRPopLPush(source string, destination string, timeout int) ([]byte, error) {
+	client, err := ring.shardForKeys(source, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.BRPopLPush(source, destination, timeout)
+}
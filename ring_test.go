@@ -0,0 +1,253 @@
+package gredis
+
+import (
+	"hash/crc32"
+	"strconv"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/valery-barysok/gredisd/app"
+	"github.com/valery-barysok/gredisd/app/gredisd"
+)
+
+func TestHashTag(t *testing.T) {
+	RegisterTestingT(t)
+
+	cases := map[string]string{
+		"key":               "key",
+		"{user1000}.name":   "user1000",
+		"prefix{user1000}":  "user1000",
+		"{}.key":            "{}.key",
+		"{key":              "{key",
+		"a{b}{c}":           "b",
+		"user{1000}.{name}": "1000",
+	}
+
+	for key, tag := range cases {
+		Expect(hashTag(key)).To(Equal(tag), key)
+	}
+}
+
+func TestHashRingMinimalMovement(t *testing.T) {
+	RegisterTestingT(t)
+
+	r := newHashRing(defaultVirtualNodes)
+	Expect(r.get("key")).To(Equal(""))
+
+	r.add("shard1")
+	r.add("shard2")
+	r.add("shard3")
+
+	const n = 10000
+	before := make([]string, n)
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		before[i] = r.get("key" + strconv.Itoa(i))
+		counts[before[i]]++
+	}
+
+	for _, cnt := range counts {
+		Expect(cnt).To(BeNumerically(">", n/6))
+	}
+
+	r.add("shard4")
+	for i := 0; i < n; i++ {
+		owner := r.get("key" + strconv.Itoa(i))
+		if owner != before[i] {
+			Expect(owner).To(Equal("shard4"))
+		}
+	}
+
+	r.remove("shard4")
+	for i := 0; i < n; i++ {
+		Expect(r.get("key" + strconv.Itoa(i))).To(Equal(before[i]))
+	}
+
+	r.remove("shard2")
+	for i := 0; i < n; i++ {
+		owner := r.get("key" + strconv.Itoa(i))
+		if before[i] != "shard2" {
+			Expect(owner).To(Equal(before[i]))
+		} else {
+			Expect(owner).ToNot(Equal("shard2"))
+		}
+	}
+
+	Expect(r.get("{tag}.a")).To(Equal(r.get("{tag}.b")))
+}
+
+func TestHashRingCollision(t *testing.T) {
+	RegisterTestingT(t)
+
+	r := newHashRing(4)
+	r.add("a")
+
+	// make the first point of "b" collide with a point of "a"
+	taken := crc32.ChecksumIEEE([]byte("b-0"))
+	r.owners[taken] = "a"
+	r.hashes = append(r.hashes, taken)
+
+	r.add("b")
+	Expect(r.owners[taken]).To(Equal("a"))
+	Expect(r.hashes).To(HaveLen(4 + 1 + 4))
+
+	owned := func(name string) int {
+		n := 0
+		for _, h := range r.hashes {
+			if r.owners[h] == name {
+				n++
+			}
+		}
+		return n
+	}
+	Expect(owned("b")).To(Equal(4))
+
+	r.remove("b")
+	Expect(r.owners[taken]).To(Equal("a"))
+	Expect(owned("a")).To(Equal(5))
+	Expect(r.hashes).To(HaveLen(5))
+}
+
+func TestRing(t *testing.T) {
+	RegisterTestingT(t)
+
+	gApp := gredisd.NewApp(&app.Options{})
+	go gApp.Run()
+	defer gApp.Shutdown()
+
+	// databases of the same server play role of separate shards
+	ring, err := NewRing("gredis://localhost/1", "gredis://localhost/2", "gredis://localhost/3")
+	Expect(err).ToNot(HaveOccurred())
+	defer ring.Close()
+
+	keys := make([]string, 0, 30)
+	for i := 0; i < 30; i++ {
+		key := "key" + strconv.Itoa(i)
+		keys = append(keys, key)
+
+		_, err := ring.Set(key, key)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	for _, rawURL := range ring.Shards() {
+		client := ring.shards[rawURL]
+		shardKeys, err := client.Keys(".*")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(shardKeys)).To(BeNumerically(">", 0), rawURL)
+	}
+
+	all, err := ring.Keys(".*")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(all).To(HaveLen(len(keys)))
+
+	exists, err := ring.Exists(keys[0], keys[1:]...)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(exists).To(Equal(len(keys)))
+
+	value, err := ring.Get("key7")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(value).To(BeEquivalentTo("key7"))
+
+	cnt, err := ring.LPush("{list}.a", "a", "b")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(cnt).To(Equal(2))
+
+	_, err = ring.HSet("{list}.b", "field", "value")
+	Expect(err).ToNot(HaveOccurred())
+
+	a, err := ring.ShardForKey("{list}.a")
+	Expect(err).ToNot(HaveOccurred())
+	b, err := ring.ShardForKey("{list}.b")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(a).To(BeIdenticalTo(b))
+
+	Expect(ring.AddShard("gredis://localhost/4")).To(Succeed())
+	Expect(ring.AddShard("gredis://localhost/4")).ToNot(Succeed())
+
+	cnt, err = ring.Del(keys[0], append(keys[1:], "{list}.a", "{list}.b")...)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(cnt).To(BeNumerically("<=", len(keys)+2))
+
+	Expect(ring.RemoveShard("gredis://localhost/4")).To(Succeed())
+	Expect(ring.RemoveShard("gredis://localhost/4")).ToNot(Succeed())
+
+	pong, err := ring.Ping()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(pong).To(Equal("PONG"))
+}
+
+func TestRingConcurrentShardChanges(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	base := "gredis://" + s.l.Addr().String() + "/"
+	ring, err := NewRing(base + "1")
+	Expect(err).ToNot(HaveOccurred())
+	defer ring.Close()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// commands racing with RemoveShard of their shard may fail with ErrClosed
+				ring.Set("key"+strconv.Itoa(i*1000+j), "value")
+				ring.Shards()
+			}
+		}(i)
+	}
+
+	for i := 0; i < 20; i++ {
+		rawURL := base + strconv.Itoa(2+i%3)
+		if err := ring.AddShard(rawURL); err == nil {
+			Expect(ring.RemoveShard(rawURL)).To(Succeed())
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	Expect(ring.Shards()).To(Equal([]string{base + "1"}))
+}
+
+func TestRingCrossShard(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(nil)
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	base := "gredis://" + s.l.Addr().String() + "/"
+	ring, err := NewRing(base+"1", base+"2", base+"3")
+	Expect(err).ToNot(HaveOccurred())
+	defer ring.Close()
+
+	first, err := ring.ShardForKey("key0")
+	Expect(err).ToNot(HaveOccurred())
+	other := ""
+	for i := 1; other == ""; i++ {
+		key := "key" + strconv.Itoa(i)
+		if client, _ := ring.ShardForKey(key); client != first {
+			other = key
+		}
+	}
+
+	_, err = ring.RPopLPush("key0", other)
+	Expect(err).To(Equal(errCrossShard))
+	_, err = ring.BRPopLPush("key0", other, 1)
+	Expect(err).To(Equal(errCrossShard))
+
+	client, err := ring.shardForKeys("{key0}.source", "{key0}.destination")
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client).To(Equal(first))
+}
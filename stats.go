@@ -0,0 +1,274 @@
+package gredis
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets are upper bounds of latency histogram buckets
+var LatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram is latency histogram. Counts[i] is the number of observations less or equal to LatencyBuckets[i]
+// and greater than the previous bound, the last element counts observations above all bounds.
+type Histogram struct {
+	Counts []uint64
+	Sum    time.Duration
+	Count  uint64
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Counts = make([]uint64, len(LatencyBuckets)+1)
+	}
+
+	i := sort.Search(len(LatencyBuckets), func(i int) bool { return d <= LatencyBuckets[i] })
+	h.Counts[i]++
+	h.Sum += d
+	h.Count++
+}
+
+// CommandStats holds statistics of a single command
+type CommandStats struct {
+	Calls   uint64
+	Errors  uint64
+	Latency Histogram
+}
+
+// Stats is a snapshot of client statistics
+type Stats struct {
+	Commands     map[string]CommandStats
+	BytesRead    uint64
+	BytesWritten uint64
+	Reconnects   uint64
+	Timeouts     uint64
+}
+
+type statsCollector struct {
+	mu       sync.Mutex
+	commands map[string]*CommandStats
+
+	bytesRead    uint64
+	bytesWritten uint64
+	reconnects   uint64
+	timeouts     uint64
+}
+
+// otherCommands is the name commands unknown to the client are counted under, so Do with arbitrary names
+// does not grow statistics without bound
+const otherCommands = "other"
+
+var knownCommands = map[string]bool{
+	"BLPOP": true,
+	"BRPOP": true,
+}
+
+func init() {
+	for _, cmd := range [][]byte{
+		AuthCommand, SelectCommand, EchoCommand, PingCommand, ShutdownCommand, CommandCommand, KeysCommand,
+		ExistsCommand, ExpireCommand, PExpireCommand, TTLCommand, TypeCommand, SetCommand, GetCommand,
+		DelCommand, IncrCommand, HSetCommand, HGetCommand, HDelCommand, HLenCommand, HExistsCommand,
+		HKeysCommand, LPushCommand, RPushCommand, LPopCommand, RPopCommand, LLenCommand, LInsertCommand,
+		LIndexCommand, LRangeCommand, LRemCommand, RPopLPushCommand, BRPopLPushCommand,
+	} {
+		knownCommands[string(cmd)] = true
+	}
+}
+
+// statsName returns name cmd is counted under: upper case name of known commands or otherCommands
+func statsName(cmd []byte) string {
+	if knownCommands[string(cmd)] {
+		return string(cmd)
+	}
+
+	if name := strings.ToUpper(string(cmd)); knownCommands[name] {
+		return name
+	}
+
+	return otherCommands
+}
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{commands: make(map[string]*CommandStats)}
+}
+
+func (s *statsCollector) record(cmd *Cmd) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := statsName(cmd.Name)
+	stats, ok := s.commands[name]
+	if !ok {
+		stats = &CommandStats{}
+		s.commands[name] = stats
+	}
+
+	stats.Calls++
+	if cmd.Err != nil {
+		stats.Errors++
+		if err, ok := cmd.Err.(net.Error); ok && err.Timeout() {
+			atomic.AddUint64(&s.timeouts, 1)
+		}
+	}
+	stats.Latency.observe(cmd.Duration)
+}
+
+func (s *statsCollector) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := Stats{
+		Commands:     make(map[string]CommandStats, len(s.commands)),
+		BytesRead:    atomic.LoadUint64(&s.bytesRead),
+		BytesWritten: atomic.LoadUint64(&s.bytesWritten),
+		Reconnects:   atomic.LoadUint64(&s.reconnects),
+		Timeouts:     atomic.LoadUint64(&s.timeouts),
+	}
+
+	for name, stats := range s.commands {
+		cp := *stats
+		cp.Latency.Counts = append([]uint64(nil), stats.Latency.Counts...)
+		res.Commands[name] = cp
+	}
+
+	return res
+}
+
+func (s *statsCollector) reconnected() {
+	atomic.AddUint64(&s.reconnects, 1)
+}
+
+// statsConn counts bytes read from and written to connection
+type statsConn struct {
+	net.Conn
+	stats *statsCollector
+}
+
+func (c *statsConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddUint64(&c.stats.bytesRead, uint64(n))
+	return n, err
+}
+
+func (c *statsConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddUint64(&c.stats.bytesWritten, uint64(n))
+	return n, err
+}
+
+// Stats returns snapshot of per-command counters and latency histograms, bytes read and written,
+// reconnects and timeouts collected by client. It is safe to call Stats concurrently with commands.
+func (client *Client) Stats() Stats {
+	return client.stats.snapshot()
+}
+
+// PublishExpvar publishes client statistics under name in expvar. It panics if name is already in use,
+// as expvar.Publish does.
+func (client *Client) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return client.Stats()
+	}))
+}
+
+// StatsHandler returns http.Handler serving client statistics in Prometheus text format
+func (client *Client) StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WritePrometheus(w, client.Stats())
+	})
+}
+
+// WritePrometheus writes stats to w in Prometheus text format
+func WritePrometheus(w io.Writer, stats Stats) error {
+	names := make([]string, 0, len(stats.Commands))
+	for name := range stats.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pw := &promWriter{w: w}
+
+	pw.header("gredis_commands_total", "counter", "Total number of commands sent.")
+	for _, name := range names {
+		pw.printf("gredis_commands_total{cmd=%q} %d\n", name, stats.Commands[name].Calls)
+	}
+
+	pw.header("gredis_command_errors_total", "counter", "Total number of failed commands.")
+	for _, name := range names {
+		pw.printf("gredis_command_errors_total{cmd=%q} %d\n", name, stats.Commands[name].Errors)
+	}
+
+	pw.header("gredis_command_duration_seconds", "histogram", "Command latency.")
+	for _, name := range names {
+		h := stats.Commands[name].Latency
+
+		var cumulative uint64
+		for i, bound := range LatencyBuckets {
+			if i < len(h.Counts) {
+				cumulative += h.Counts[i]
+			}
+			le := strconv.FormatFloat(bound.Seconds(), 'g', -1, 64)
+			pw.printf("gredis_command_duration_seconds_bucket{cmd=%q,le=%q} %d\n", name, le, cumulative)
+		}
+		pw.printf("gredis_command_duration_seconds_bucket{cmd=%q,le=\"+Inf\"} %d\n", name, h.Count)
+		pw.printf("gredis_command_duration_seconds_sum{cmd=%q} %g\n", name, h.Sum.Seconds())
+		pw.printf("gredis_command_duration_seconds_count{cmd=%q} %d\n", name, h.Count)
+	}
+
+	pw.header("gredis_bytes_read_total", "counter", "Total number of bytes read from connection.")
+	pw.printf("gredis_bytes_read_total %d\n", stats.BytesRead)
+
+	pw.header("gredis_bytes_written_total", "counter", "Total number of bytes written to connection.")
+	pw.printf("gredis_bytes_written_total %d\n", stats.BytesWritten)
+
+	pw.header("gredis_reconnects_total", "counter", "Total number of reconnects.")
+	pw.printf("gredis_reconnects_total %d\n", stats.Reconnects)
+
+	pw.header("gredis_timeouts_total", "counter", "Total number of timed out commands.")
+	pw.printf("gredis_timeouts_total %d\n", stats.Timeouts)
+
+	return pw.err
+}
+
+// promWriter remembers the first write error
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *promWriter) header(name string, typ string, help string) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (pw *promWriter) printf(format string, args ...interface{}) {
+	if pw.err != nil {
+		return
+	}
+
+	_, pw.err = fmt.Fprintf(pw.w, format, args...)
+}
@@ -0,0 +1,74 @@
+package gredis
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestStatsCollector(t *testing.T) {
+	RegisterTestingT(t)
+
+	s := newStatsCollector()
+	s.record(&Cmd{Name: GetCommand, Duration: 50 * time.Microsecond})
+	s.record(&Cmd{Name: GetCommand, Duration: 3 * time.Millisecond, Err: errors.New("ERR")})
+	s.record(&Cmd{Name: SetCommand, Duration: time.Minute, Err: timeoutError{}})
+
+	stats := s.snapshot()
+	Expect(stats.Timeouts).To(Equal(uint64(1)))
+
+	get := stats.Commands["GET"]
+	Expect(get.Calls).To(Equal(uint64(2)))
+	Expect(get.Errors).To(Equal(uint64(1)))
+	Expect(get.Latency.Count).To(Equal(uint64(2)))
+	Expect(get.Latency.Sum).To(Equal(3050 * time.Microsecond))
+	Expect(get.Latency.Counts[0]).To(Equal(uint64(1)))
+	Expect(get.Latency.Counts[5]).To(Equal(uint64(1)))
+
+	set := stats.Commands["SET"]
+	Expect(set.Latency.Counts[len(LatencyBuckets)]).To(Equal(uint64(1)))
+
+	// snapshot must not share counters with collector
+	s.record(&Cmd{Name: GetCommand})
+	Expect(get.Latency.Counts[0]).To(Equal(uint64(1)))
+
+	// unknown names do not add entries
+	s.record(&Cmd{Name: []byte("get")})
+	s.record(&Cmd{Name: []byte("FOO1")})
+	s.record(&Cmd{Name: []byte("FOO2")})
+	stats = s.snapshot()
+	Expect(stats.Commands).To(HaveLen(3))
+	Expect(stats.Commands["GET"].Calls).To(Equal(uint64(4)))
+	Expect(stats.Commands[otherCommands].Calls).To(Equal(uint64(2)))
+}
+
+func TestWritePrometheus(t *testing.T) {
+	RegisterTestingT(t)
+
+	s := newStatsCollector()
+	s.record(&Cmd{Name: GetCommand, Duration: 200 * time.Microsecond})
+	s.bytesRead = 10
+	s.bytesWritten = 20
+
+	var buf bytes.Buffer
+	Expect(WritePrometheus(&buf, s.snapshot())).To(Succeed())
+
+	lines := strings.Split(buf.String(), "\n")
+	Expect(lines).To(ContainElement(`gredis_commands_total{cmd="GET"} 1`))
+	Expect(lines).To(ContainElement(`gredis_command_duration_seconds_bucket{cmd="GET",le="0.0001"} 0`))
+	Expect(lines).To(ContainElement(`gredis_command_duration_seconds_bucket{cmd="GET",le="0.00025"} 1`))
+	Expect(lines).To(ContainElement(`gredis_command_duration_seconds_bucket{cmd="GET",le="+Inf"} 1`))
+	Expect(lines).To(ContainElement(`gredis_bytes_read_total 10`))
+	Expect(lines).To(ContainElement(`gredis_bytes_written_total 20`))
+	Expect(lines).To(ContainElement(`# TYPE gredis_command_duration_seconds histogram`))
+}
@@ -0,0 +1,369 @@
+package gredis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// delayServer replies with +OK to every command, delaying replies of commands listed in delays
+type delayServer struct {
+	l      net.Listener
+	delays map[string]time.Duration
+}
+
+func newDelayServer(delays map[string]time.Duration) (*delayServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &delayServer{l: l, delays: delays}
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *delayServer) serve() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *delayServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		n, err := readBenchInt(r)
+		if err != nil {
+			return
+		}
+
+		var name string
+		for i := 0; i < n; i++ {
+			size, err := readBenchInt(r)
+			if err != nil {
+				return
+			}
+
+			arg := make([]byte, size+2)
+			if _, err := io.ReadFull(r, arg); err != nil {
+				return
+			}
+			if i == 0 {
+				name = string(arg[:size])
+			}
+		}
+
+		time.Sleep(s.delays[name])
+		if _, err := conn.Write([]byte("+OK\r\n")); err != nil {
+			return
+		}
+	}
+}
+
+func (s *delayServer) dial(opts *Options) (*Client, error) {
+	opts.Host, opts.Port, _ = net.SplitHostPort(s.l.Addr().String())
+	return Dial(opts)
+}
+
+func TestCommandTimeouts(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(map[string]time.Duration{
+		"KEYS":       200 * time.Millisecond,
+		"BRPOPLPUSH": 200 * time.Millisecond,
+		"GET":        200 * time.Millisecond,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	newOptions := func() *Options {
+		opts, err := NewOptions("gredis://localhost")
+		Expect(err).ToNot(HaveOccurred())
+		opts.ReadTimeout = 50 * time.Millisecond
+		return opts
+	}
+
+	// slow command fails with connection defaults
+	client, err := s.dial(newOptions())
+	Expect(err).ToNot(HaveOccurred())
+	_, err = client.Keys(".*")
+	Expect(err).To(HaveOccurred())
+	client.Close()
+
+	// per-command timeout overrides connection defaults
+	opts := newOptions()
+	opts.CommandTimeouts = map[string]time.Duration{"KEYS": time.Second}
+	client, err = s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	_, err = client.Keys(".*")
+	Expect(err).ToNot(HaveOccurred())
+
+	// blocking commands get no read deadline by default
+	_, err = client.Do(RPopLPushCommand, []byte("src"), []byte("dst"))
+	Expect(err).ToNot(HaveOccurred())
+	_, err = client.Do([]byte("BRPOPLPUSH"), []byte("src"), []byte("dst"), []byte("0"))
+	Expect(err).ToNot(HaveOccurred())
+
+	// context without deadline keeps connection defaults
+	_, err = client.DoContext(context.Background(), GetCommand, []byte("key"))
+	Expect(err).To(HaveOccurred())
+	client.Close()
+
+	// deadline of context overrides all timeouts
+
+	client, err = s.dial(newOptions())
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = client.DoContext(ctx, GetCommand, []byte("key"))
+	Expect(err).ToNot(HaveOccurred())
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = client.DoContext(ctx, []byte("BRPOPLPUSH"), []byte("src"), []byte("dst"), []byte("0"))
+	Expect(err).To(Equal(context.DeadlineExceeded))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.DoContext(canceled, PingCommand)
+	Expect(err).To(Equal(context.Canceled))
+}
+
+// stalledServer accepts connections but never reads from them nor replies
+type stalledServer struct {
+	l net.Listener
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newStalledServer() (*stalledServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &stalledServer{l: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *stalledServer) Close() {
+	s.l.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// within fails test if fn does not return in time, so a deadline which does not fire can not hang tests
+func within(t *testing.T, timeout time.Duration, fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatal("deadline did not fire")
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newStalledServer()
+	Expect(err).ToNot(HaveOccurred())
+	defer s.Close()
+
+	opts, err := NewOptions("gredis://" + s.l.Addr().String())
+	Expect(err).ToNot(HaveOccurred())
+	opts.ReadTimeout = time.Hour
+	opts.WriteTimeout = 50 * time.Millisecond
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	// the value does not fit into socket buffers of server which does not read
+	value := make([]byte, 64<<20)
+	within(t, 5*time.Second, func() {
+		_, err = client.SetBytes("key", value)
+	})
+
+	Expect(errors.Is(err, ErrTimeout)).To(BeTrue())
+	Expect(err.(*TimeoutError).Op).To(Equal("write"))
+	Expect(client.broken).To(BeTrue())
+}
+
+func TestReadTimeout(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newStalledServer()
+	Expect(err).ToNot(HaveOccurred())
+	defer s.Close()
+
+	opts, err := NewOptions("gredis://" + s.l.Addr().String())
+	Expect(err).ToNot(HaveOccurred())
+	opts.ReadTimeout = 50 * time.Millisecond
+	opts.WriteTimeout = time.Hour
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	within(t, 5*time.Second, func() {
+		_, err = client.Get("key")
+	})
+
+	Expect(errors.Is(err, ErrTimeout)).To(BeTrue())
+	Expect(err.(*TimeoutError).Op).To(Equal("read"))
+	Expect(client.Stats().Timeouts).To(Equal(uint64(1)))
+}
+
+func TestTimeoutReplacesConnection(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(map[string]time.Duration{"GET": 200 * time.Millisecond})
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+	opts.ReadTimeout = 50 * time.Millisecond
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	_, err = client.Get("key")
+	Expect(errors.Is(err, ErrTimeout)).To(BeTrue())
+
+	// late reply of GET must not be taken for reply of PING
+	time.Sleep(200 * time.Millisecond)
+	_, err = client.Ping()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client.broken).To(BeFalse())
+	Expect(client.Stats().Reconnects).To(Equal(uint64(1)))
+}
+
+func TestDoContextInterruptsCommand(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(map[string]time.Duration{"BRPOPLPUSH": 500 * time.Millisecond})
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	within(t, 5*time.Second, func() {
+		_, err = client.DoContext(ctx, []byte("BRPOPLPUSH"), []byte("src"), []byte("dst"), []byte("0"))
+	})
+	Expect(err).To(Equal(context.Canceled))
+	Expect(time.Since(start)).To(BeNumerically("<", 400*time.Millisecond))
+
+	// interrupted connection is replaced
+	_, err = client.Ping()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client.Stats().Reconnects).To(Equal(uint64(1)))
+}
+
+func TestDoContextKeepsTimeoutError(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newStalledServer()
+	Expect(err).ToNot(HaveOccurred())
+	defer s.Close()
+
+	opts, err := NewOptions("gredis://" + s.l.Addr().String())
+	Expect(err).ToNot(HaveOccurred())
+	opts.WriteTimeout = 50 * time.Millisecond
+
+	client, err := Dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	// write timeout fires while ctx is still alive
+	value := make([]byte, 64<<20)
+	within(t, 5*time.Second, func() {
+		_, err = client.DoContext(ctx, SetCommand, []byte("key"), value)
+	})
+	Expect(errors.Is(err, ErrTimeout)).To(BeTrue())
+	Expect(err.(*TimeoutError).Op).To(Equal("write"))
+}
+
+func TestLowLevelAfterTimeout(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, err := newDelayServer(map[string]time.Duration{"GET": 200 * time.Millisecond})
+	Expect(err).ToNot(HaveOccurred())
+	defer s.l.Close()
+
+	opts, err := NewOptions("gredis://localhost")
+	Expect(err).ToNot(HaveOccurred())
+	opts.ReadTimeout = 50 * time.Millisecond
+
+	client, err := s.dial(opts)
+	Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	Expect(client.Send(GetCommand, []byte("key"))).To(Succeed())
+	_, err = client.Receive()
+	Expect(errors.Is(err, ErrTimeout)).To(BeTrue())
+
+	// reply of GET is lost with the connection
+	_, err = client.Receive()
+	Expect(err).To(Equal(ErrBroken))
+
+	// the next command replaces the connection
+	Expect(client.Send(PingCommand)).To(Succeed())
+	Expect(client.Flush()).To(Succeed())
+	_, err = client.Receive()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(client.Stats().Reconnects).To(Equal(uint64(1)))
+}
@@ -0,0 +1,140 @@
+package gredis
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultTraceMaxLen = 256
+
+var (
+	traceConnID  uint64
+	authToken    = []byte("AUTH")
+	redactedText = []byte("(redacted)")
+)
+
+// States of AUTH command written as separate bulk lines
+const (
+	authNone = iota
+	// authHeader expects `$<len>` header of the password bulk
+	authHeader
+	// authPassword expects the password itself
+	authPassword
+)
+
+// traceWriter filters protocol trace of a single connection before passing it to the destination writer:
+// every line is prefixed with connection id, client name and remote address, the password of `AUTH`
+// command is redacted and lines longer than maxLen are truncated.
+type traceWriter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	prefix    []byte
+	maxLen    int
+	partial   []byte
+	authState int
+}
+
+// newTraceWriter returns trace writer of connection to remoteAddr, name of client is added to the prefix
+// when not empty
+func newTraceWriter(w io.Writer, name string, remoteAddr string, maxLen int) *traceWriter {
+	id := strconv.FormatUint(atomic.AddUint64(&traceConnID, 1), 10)
+	if name != "" {
+		id += " " + name
+	}
+
+	if maxLen == 0 {
+		maxLen = defaultTraceMaxLen
+	}
+
+	return &traceWriter{
+		w:      w,
+		prefix: []byte(fmt.Sprintf("[conn %s %s] ", id, remoteAddr)),
+		maxLen: maxLen,
+	}
+}
+
+func (tw *traceWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.partial = append(tw.partial, p...)
+
+	var out []byte
+	for {
+		i := bytes.IndexByte(tw.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		out = tw.appendLine(out, tw.partial[:i])
+		tw.partial = tw.partial[i+1:]
+	}
+
+	if len(tw.partial) == 0 {
+		tw.partial = nil
+	}
+
+	if len(out) != 0 {
+		if _, err := tw.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (tw *traceWriter) appendLine(out []byte, line []byte) []byte {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+
+	out = append(out, tw.prefix...)
+
+	if tw.authState == authHeader && len(line) != 0 && line[0] == '$' {
+		// length of the password is not secret
+		tw.authState = authPassword
+		out = append(out, line...)
+	} else if tw.authState != authNone {
+		// password of AUTH command written as a separate bulk line, whatever it starts with
+		tw.authState = authNone
+		out = append(out, redactedText...)
+	} else if i := tw.redact(line); i >= 0 {
+		out = append(out, line[:i]...)
+		out = append(out, redactedText...)
+	} else if tw.maxLen > 0 && len(line) > tw.maxLen {
+		out = append(out, line[:tw.maxLen]...)
+		out = append(out, fmt.Sprintf("... (%d bytes)", len(line))...)
+	} else {
+		out = append(out, line...)
+	}
+
+	return append(out, '\n')
+}
+
+// redact returns offset where the password starts when line holds `AUTH` command with its argument,
+// otherwise -1. A line with standalone `AUTH` makes the line following the next `$<len>` header redacted.
+func (tw *traceWriter) redact(line []byte) int {
+	upper := bytes.ToUpper(line)
+
+	i := bytes.Index(upper, authToken)
+	if i < 0 || (i > 0 && isWordByte(upper[i-1])) {
+		return -1
+	}
+
+	rest := upper[i+len(authToken):]
+	if len(bytes.TrimSpace(rest)) == 0 {
+		tw.authState = authHeader
+		return -1
+	}
+	if isWordByte(rest[0]) {
+		return -1
+	}
+
+	return i + len(authToken) + 1
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z'
+}
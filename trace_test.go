@@ -0,0 +1,89 @@
+package gredis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestTraceWriter(t *testing.T) {
+	RegisterTestingT(t)
+
+	cases := []struct {
+		in  string
+		out []string
+	}{
+		{
+			"*2\r\n$4\r\nAUTH\r\n$8\r\npassword\r\n",
+			[]string{"*2", "$4", "AUTH", "$8", "(redacted)"},
+		},
+		{
+			"*2\r\n$4\r\nAUTH\r\n$7\r\n$ecret1\r\n*2\r\n$3\r\nGET\r\n",
+			[]string{"*2", "$4", "AUTH", "$7", "(redacted)", "*2", "$3", "GET"},
+		},
+		{
+			"AUTH password\n+OK\n",
+			[]string{"AUTH (redacted)", "+OK"},
+		},
+		{
+			"*2\r\n$3\r\nGET\r\n$6\r\nAUTHOR\r\n",
+			[]string{"*2", "$3", "GET", "$6", "AUTHOR"},
+		},
+		{
+			"$300\r\n" + strings.Repeat("x", 300) + "\r\n",
+			[]string{"$300", strings.Repeat("x", 256) + "... (300 bytes)"},
+		},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		tw := newTraceWriter(&buf, "", "127.0.0.1:16379", 0)
+
+		// split input to make sure partial lines are handled
+		for i := 0; i < len(c.in); i += 5 {
+			end := i + 5
+			if end > len(c.in) {
+				end = len(c.in)
+			}
+
+			n, err := tw.Write([]byte(c.in[i:end]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(end - i))
+		}
+
+		prefix := string(tw.prefix)
+		Expect(prefix).To(HaveSuffix(" 127.0.0.1:16379] "))
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if Expect(lines).To(HaveLen(len(c.out)), c.in) {
+			for i, line := range lines {
+				Expect(line).To(Equal(prefix+c.out[i]), c.in)
+			}
+		}
+	}
+}
+
+func TestTraceWriterWithoutTruncation(t *testing.T) {
+	RegisterTestingT(t)
+
+	var buf bytes.Buffer
+	tw := newTraceWriter(&buf, "", "addr", -1)
+
+	line := strings.Repeat("x", 1000)
+	tw.Write([]byte(line + "\n"))
+
+	Expect(buf.String()).To(Equal(string(tw.prefix) + line + "\n"))
+}
+
+func TestTraceWriterWithClientName(t *testing.T) {
+	RegisterTestingT(t)
+
+	var buf bytes.Buffer
+	tw := newTraceWriter(&buf, "worker-1", "addr", 0)
+	tw.Write([]byte("+OK\r\n"))
+
+	Expect(string(tw.prefix)).To(HaveSuffix(" worker-1 addr] "))
+	Expect(buf.String()).To(Equal(string(tw.prefix) + "+OK\n"))
+}